@@ -0,0 +1,245 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RouteConfig 声明式的路由配置，可以从 YAML 或者 JSON 文件读取，比如：
+//
+//	routes:
+//	  - path: /user/:id
+//	    methods: [GET]
+//	    handler: user.get
+//	    middlewares: [auth]
+//	    name: user.detail
+//	    aliases: [/users/:id]
+//	  - path: /v1/user/:id
+//	    methods: [GET]
+//	    handler: user.get
+//	    deprecated: true
+//	    sunset: Sat, 01 Nov 2025 00:00:00 GMT
+type RouteConfig struct {
+	Routes []RouteSpec `json:"routes" yaml:"routes"`
+}
+
+// RouteSpec 一条路由的配置
+type RouteSpec struct {
+	// Host 为空表示注册在默认路由上，否则注册在 HttpServer.Host(Host) 上
+	Host    string   `json:"host,omitempty" yaml:"host,omitempty"`
+	Path    string   `json:"path" yaml:"path"`
+	Methods []string `json:"methods" yaml:"methods"`
+	// Handler 和 Middlewares 都是 Registry 里注册的名字，
+	// Middlewares 按顺序执行，第一个在最外层
+	Handler     string   `json:"handler" yaml:"handler"`
+	Middlewares []string `json:"middlewares,omitempty" yaml:"middlewares,omitempty"`
+	// Name 路由的名字，见 Route.Name
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Aliases 使用同一个 handler 的其它路径，比如调整 URL 之后保留的旧路径
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// Deprecated 为 true 的时候响应里会带上 Deprecation 头，
+	// 如果设置了 Sunset 也会带上 Sunset 头，告诉调用方什么时候下线
+	Deprecated bool   `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	Sunset     string `json:"sunset,omitempty" yaml:"sunset,omitempty"`
+}
+
+// Registry 配置里面引用的 handler 和 middleware 的名字
+type Registry struct {
+	handlers    map[string]HandleFunc
+	middlewares map[string]Middleware
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers:    map[string]HandleFunc{},
+		middlewares: map[string]Middleware{},
+	}
+}
+
+// Handler 注册一个 handler，名字重复的时候覆盖
+func (r *Registry) Handler(name string, fn HandleFunc) *Registry {
+	r.handlers[name] = fn
+	return r
+}
+
+// Middleware 注册一个 middleware，名字重复的时候覆盖
+func (r *Registry) Middleware(name string, m Middleware) *Registry {
+	r.middlewares[name] = m
+	return r
+}
+
+// ReadRouteConfig 读取路由配置文件，后缀是 .json 的按照 JSON 解析，其余的按照 YAML 解析
+func ReadRouteConfig(path string) (*RouteConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &RouteConfig{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, cfg)
+	} else {
+		err = yaml.Unmarshal(data, cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("web: 解析路由配置 %s 失败: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// RouteConfigErrors 路由配置里所有的错误，方便一次性修改
+type RouteConfigErrors []error
+
+func (e RouteConfigErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// LoadRoutes 按照配置注册路由。
+// 先把已经注册的路由复制到新的路由树上，按照 Router.AddRoute 的规则校验整个配置，
+// 同时检查 handler、middleware 的名字和路由的名字，包括和已经注册的名字冲突，
+// 有任何错误都不会注册，所有的错误放在 RouteConfigErrors 里一起返回。
+// 校验通过之后再注册到 HttpServer 上，如果这时候还是失败了，比如同时有别的地方在注册路由，
+// 已经注册的路由会被删除，Router 不支持删除的时候可能会留下一部分
+func (h *HttpServer) LoadRoutes(cfg *RouteConfig, reg *Registry) error {
+	type entry struct {
+		host    string
+		method  string
+		path    string
+		name    string
+		handler HandleFunc
+		// handlerName 套上 middleware 之前的 handler 的名字
+		handlerName string
+	}
+	var (
+		errs    RouteConfigErrors
+		entries []entry
+	)
+	// 按域名分开校验，已经注册的路由也放进去，这样和它们冲突也能提前发现
+	scratch := map[string]Router{}
+	for _, ri := range h.Routes() {
+		r, ok := scratch[ri.Host]
+		if !ok {
+			r = NewTreeRouter()
+			scratch[ri.Host] = r
+		}
+		_ = r.AddRoute(ri.Method, ri.Pattern, ri.Handler)
+	}
+	names := map[string]bool{}
+	h.mu.Lock()
+	for name := range h.names {
+		names[name] = true
+	}
+	h.mu.Unlock()
+	for i, spec := range cfg.Routes {
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("web: 第 %d 个路由 [%s]: %s", i+1, spec.Path, fmt.Sprintf(format, args...)))
+		}
+		fn, ok := reg.handlers[spec.Handler]
+		if !ok {
+			fail("handler [%s] 没有注册", spec.Handler)
+		}
+		mdls := make([]Middleware, 0, len(spec.Middlewares))
+		for _, name := range spec.Middlewares {
+			m, ok := reg.middlewares[name]
+			if !ok {
+				fail("middleware [%s] 没有注册", name)
+				continue
+			}
+			mdls = append(mdls, m)
+		}
+		if len(spec.Methods) == 0 {
+			fail("没有指定 HTTP 方法")
+		}
+		if spec.Host != "" {
+			if err := validHost(spec.Host); err != nil {
+				errs = append(errs, fmt.Errorf("web: 第 %d 个路由 [%s]: %w", i+1, spec.Path, err))
+			}
+		}
+		if spec.Name != "" {
+			if names[spec.Name] {
+				fail("路由名字 [%s] 重复", spec.Name)
+			}
+			names[spec.Name] = true
+		}
+		if ok && spec.Deprecated {
+			mdls = append([]Middleware{deprecation(spec.Sunset)}, mdls...)
+		}
+		handlerName := funcName(fn)
+		if ok {
+			fn = chain(fn, mdls...)
+		}
+
+		// HttpServer.Host 会把域名转成小写
+		host := strings.ToLower(spec.Host)
+		r, ok := scratch[host]
+		if !ok {
+			r = NewTreeRouter()
+			scratch[host] = r
+		}
+		for k, method := range spec.Methods {
+			method = strings.ToUpper(method)
+			for j, path := range append([]string{spec.Path}, spec.Aliases...) {
+				if err := r.AddRoute(method, path, fn); err != nil {
+					errs = append(errs, fmt.Errorf("web: 第 %d 个路由 %s [%s]: %w", i+1, method, path, err))
+					continue
+				}
+				e := entry{host: spec.Host, method: method, path: path, handler: fn, handlerName: handlerName}
+				// 名字只给主路径，别名不参与 URL 的生成
+				if j == 0 && k == 0 {
+					e.name = spec.Name
+				}
+				entries = append(entries, e)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	for i, e := range entries {
+		var hr *hostRoutes
+		if e.host != "" {
+			hr = h.Host(e.host).host
+		}
+		route, err := h.handle(hr, e.method, e.path, e.handler, e.handlerName, nil)
+		if err == nil && e.name != "" {
+			if err = route.setName(e.name); err != nil {
+				// 路由已经注册上了，要和前面的一起删除
+				i++
+			}
+		}
+		if err != nil {
+			for _, e := range entries[:i] {
+				if e.host == "" {
+					h.RemoveRoute(e.method, e.path)
+				} else {
+					h.Host(e.host).RemoveRoute(e.method, e.path)
+				}
+			}
+			return RouteConfigErrors{fmt.Errorf("web: %s [%s]: %w", e.method, e.path, err)}
+		}
+	}
+	return nil
+}
+
+// deprecation 给过时的路由加上 Deprecation 和 Sunset 响应头
+func deprecation(sunset string) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			header := ctx.Resp.Header()
+			header.Set("Deprecation", "true")
+			if sunset != "" {
+				header.Set("Sunset", sunset)
+			}
+			next(ctx)
+		}
+	}
+}
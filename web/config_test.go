@@ -0,0 +1,215 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRegistry() *Registry {
+	return NewRegistry().
+		Handler("user.get", func(ctx *Context) {
+			id, _ := ctx.PathValue("id")
+			_, _ = ctx.Resp.Write([]byte("user " + id))
+		}).
+		Middleware("tag", func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				ctx.Resp.Header().Add("X-Tag", "tag")
+				next(ctx)
+			}
+		})
+}
+
+func TestReadRouteConfig(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "routes.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte(`
+routes:
+  - path: /user/:id
+    methods: [get]
+    handler: user.get
+    middlewares: [tag]
+    name: user.detail
+    aliases: [/users/:id]
+`), 0o644))
+	jsonFile := filepath.Join(dir, "routes.json")
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`{"routes": [
+		{"path": "/user/:id", "methods": ["get"], "handler": "user.get",
+		 "middlewares": ["tag"], "name": "user.detail", "aliases": ["/users/:id"]}
+	]}`), 0o644))
+
+	want := &RouteConfig{Routes: []RouteSpec{{
+		Path:        "/user/:id",
+		Methods:     []string{"get"},
+		Handler:     "user.get",
+		Middlewares: []string{"tag"},
+		Name:        "user.detail",
+		Aliases:     []string{"/users/:id"},
+	}}}
+	for _, file := range []string{yamlFile, jsonFile} {
+		cfg, err := ReadRouteConfig(file)
+		require.NoError(t, err)
+		assert.Equal(t, want, cfg)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	require.NoError(t, os.WriteFile(bad, []byte(`{`), 0o644))
+	_, err := ReadRouteConfig(bad)
+	assert.Error(t, err)
+}
+
+func TestHttpServer_LoadRoutes(t *testing.T) {
+	h := NewHTTPServer()
+	err := h.LoadRoutes(&RouteConfig{Routes: []RouteSpec{
+		{
+			Path: "/user/:id", Methods: []string{"get"}, Handler: "user.get",
+			Middlewares: []string{"tag"}, Name: "user.detail", Aliases: []string{"/users/:id"},
+		},
+		{
+			Path: "/v1/user/:id", Methods: []string{http.MethodGet}, Handler: "user.get",
+			Deprecated: true, Sunset: "Sat, 01 Nov 2025 00:00:00 GMT",
+		},
+		{Host: "api.example.com", Path: "/user/:id", Methods: []string{http.MethodGet}, Handler: "user.get"},
+	}}, testRegistry())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		host       string
+		path       string
+		wantBody   string
+		wantHeader http.Header
+	}{
+		{
+			name: "path", path: "/user/123", wantBody: "user 123",
+			wantHeader: http.Header{"X-Tag": {"tag"}},
+		},
+		{
+			name: "alias", path: "/users/123", wantBody: "user 123",
+			wantHeader: http.Header{"X-Tag": {"tag"}},
+		},
+		{
+			name: "deprecated", path: "/v1/user/123", wantBody: "user 123",
+			wantHeader: http.Header{
+				"Deprecation": {"true"},
+				"Sunset":      {"Sat, 01 Nov 2025 00:00:00 GMT"},
+			},
+		},
+		{name: "host", host: "api.example.com", path: "/user/123", wantBody: "user 123"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.host != "" {
+				req.Host = tc.host
+			}
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			for key := range tc.wantHeader {
+				assert.Equal(t, tc.wantHeader.Get(key), recorder.Header().Get(key))
+			}
+		})
+	}
+
+	u, err := h.URL("user.detail", "id", "123")
+	require.NoError(t, err)
+	assert.Equal(t, "/user/123", u)
+}
+
+func TestHttpServer_LoadRoutes_Errors(t *testing.T) {
+	h := NewHTTPServer()
+	err := h.LoadRoutes(&RouteConfig{Routes: []RouteSpec{
+		{Path: "/a", Methods: []string{http.MethodGet}, Handler: "user.get", Name: "a"},
+		{Path: "/b", Methods: []string{http.MethodGet}, Handler: "missing", Middlewares: []string{"nope"}, Name: "a"},
+		{Path: "/c/", Methods: []string{http.MethodGet}, Handler: "user.get"},
+		{Path: "/a", Handler: "user.get"},
+		{Path: "/d", Methods: []string{http.MethodGet}, Handler: "user.get", Aliases: []string{"/a"}},
+	}}, testRegistry())
+
+	var errs RouteConfigErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 6)
+	assert.EqualError(t, err, "web: 第 2 个路由 [/b]: handler [missing] 没有注册\n"+
+		"web: 第 2 个路由 [/b]: middleware [nope] 没有注册\n"+
+		"web: 第 2 个路由 [/b]: 路由名字 [a] 重复\n"+
+		"web: 第 3 个路由 GET [/c/]: web：路径不能以 / 结尾\n"+
+		"web: 第 4 个路由 [/a]: 没有指定 HTTP 方法\n"+
+		"web: 第 5 个路由 GET [/a]: web: 路径冲突，重复注册[/a]")
+	assert.ErrorIs(t, errs[3], ErrPathTrailingSlash)
+	var conflict *ErrConflict
+	assert.ErrorAs(t, errs[5], &conflict)
+
+	// 校验失败的时候一个路由都不注册
+	assert.Empty(t, h.Routes())
+
+	// 和代码里注册的路由冲突
+	h.Get("/a", func(ctx *Context) {})
+	err = h.LoadRoutes(&RouteConfig{Routes: []RouteSpec{
+		{Path: "/a", Methods: []string{http.MethodGet}, Handler: "user.get"},
+		{Path: "/b", Methods: []string{http.MethodGet}, Handler: "user.get"},
+	}}, testRegistry())
+	assert.EqualError(t, err, "web: 第 1 个路由 GET [/a]: web: 路径冲突，重复注册[/a]")
+	assert.Len(t, h.Routes(), 1)
+
+	// 和代码里注册的路由名字冲突
+	h.Host("API.example.com").Get("/c", func(ctx *Context) {}).Name("c")
+	err = h.LoadRoutes(&RouteConfig{Routes: []RouteSpec{
+		{Path: "/b", Methods: []string{http.MethodGet}, Handler: "user.get", Name: "c"},
+		{Host: "api.EXAMPLE.com", Path: "/c", Methods: []string{http.MethodGet}, Handler: "user.get"},
+	}}, testRegistry())
+	assert.EqualError(t, err, "web: 第 1 个路由 [/b]: 路由名字 [c] 重复\n"+
+		"web: 第 2 个路由 GET [/c]: web: 路径冲突，重复注册[/c]")
+	assert.Len(t, h.Routes(), 2)
+
+	// 域名不合法的时候在校验阶段返回错误，而不是注册到一半 panic
+	err = h.LoadRoutes(&RouteConfig{Routes: []RouteSpec{
+		{Path: "/d", Methods: []string{http.MethodGet}, Handler: "user.get"},
+		{Host: "api.example.com:8080", Path: "/d", Methods: []string{http.MethodGet}, Handler: "user.get"},
+	}}, testRegistry())
+	assert.EqualError(t, err, "web: 第 2 个路由 [/d]: web: 域名 [api.example.com:8080] 不能带端口")
+	assert.Len(t, h.Routes(), 2)
+}
+
+// rejectRouter 拒绝注册某个路径，模拟校验通过之后注册失败
+type rejectRouter struct {
+	Router
+	reject string
+}
+
+func (r *rejectRouter) AddRoute(method string, path string, handleFunc HandleFunc) error {
+	if path == r.reject {
+		return errors.New("web: 拒绝注册")
+	}
+	return r.Router.AddRoute(method, path, handleFunc)
+}
+
+func (r *rejectRouter) RemoveRoute(method string, path string) bool {
+	return r.Router.(RouteRemover).RemoveRoute(method, path)
+}
+
+func TestHttpServer_LoadRoutes_Rollback(t *testing.T) {
+	h := NewHTTPServer(WithRouter(&rejectRouter{Router: NewTreeRouter(), reject: "/c"}))
+	h.Get("/x", func(ctx *Context) {}).Name("x")
+	err := h.LoadRoutes(&RouteConfig{Routes: []RouteSpec{
+		{Path: "/a", Methods: []string{http.MethodGet}, Handler: "user.get", Name: "a"},
+		{Path: "/b", Methods: []string{http.MethodGet, http.MethodPost}, Handler: "user.get"},
+		{Path: "/c", Methods: []string{http.MethodGet}, Handler: "user.get"},
+	}}, testRegistry())
+	assert.EqualError(t, err, "web: GET [/c]: web: 拒绝注册")
+
+	// 已经注册的配置路由都被删除，代码里注册的路由不受影响
+	routes := h.Routes()
+	require.Len(t, routes, 1)
+	assert.Equal(t, "/x", routes[0].Pattern)
+	_, err = h.URL("a")
+	assert.EqualError(t, err, "web: 找不到名字为 [a] 的路由")
+	_, err = h.URL("x")
+	assert.NoError(t, err)
+}
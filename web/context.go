@@ -1,10 +1,172 @@
 package web
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
 
+// Context 一次请求的上下文。
+// 注意 handler 返回之后 Context 会被复用，不能在新启动的 goroutine 里面使用它，
+// 需要 context.Context 的时候用 Context() 方法
 type Context struct {
 	Req  *http.Request
 	Resp http.ResponseWriter
 
-	pathParams map[string]string
+	// 路由查找的结果，路径参数在 match.Params 里。
+	// 用切片而不是 map，Context 复用的时候可以直接复用底层数组
+	match Match
+
+	// Set 设置的值，在 middleware 和 handler 之间传递数据
+	keys map[string]any
+
+	requestID string
+
+	// ctx 是 Context() 返回的结果，Set 或者替换了 Req 之后重新生成
+	ctx    context.Context
+	ctxReq *http.Request
+}
+
+// Param 路径参数
+type Param struct {
+	Key   string
+	Value string
+}
+
+// PathValue 返回路径参数 key 的值
+func (c *Context) PathValue(key string) (string, bool) {
+	for _, p := range c.match.Params {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Route 返回命中的路由，也就是注册时的路径，比如 /user/:id，没有命中的时候返回空字符串。
+// 在全局的 middleware 里面，要等 next 返回之后才有值
+func (c *Context) Route() string {
+	return c.match.Pattern
+}
+
+// RequestID 返回请求 ID，来自请求头或者新生成的，见 WithRequestIDHeader
+func (c *Context) RequestID() string {
+	return c.requestID
+}
+
+// PathParams 返回所有的路径参数，按照在路径里出现的顺序排列。
+// 返回的切片在 handler 返回之后会被复用，需要保留的话自己复制一份
+func (c *Context) PathParams() []Param {
+	return c.match.Params
+}
+
+// Set 保存一个只在这次请求里面有效的值，比如鉴权之后的用户 ID
+func (c *Context) Set(key string, val any) {
+	if c.keys == nil {
+		c.keys = make(map[string]any)
+	}
+	c.keys[key] = val
+	c.ctx = nil
+}
+
+// Get 返回 Set 保存的值
+func (c *Context) Get(key string) (any, bool) {
+	val, ok := c.keys[key]
+	return val, ok
+}
+
+// MustGet 和 Get 一样，但是没有这个 key 的时候 panic
+func (c *Context) MustGet(key string) any {
+	val, ok := c.keys[key]
+	if !ok {
+		panic(fmt.Sprintf("web: Context 里面没有 key [%s]", key))
+	}
+	return val
+}
+
+// GetAs 返回 Set 保存的值并转换成 T，没有这个 key 或者类型不对的时候返回 false
+func GetAs[T any](c *Context, key string) (T, bool) {
+	val, ok := c.keys[key].(T)
+	return val, ok
+}
+
+// MustGetAs 和 GetAs 一样，但是没有这个 key 或者类型不对的时候 panic
+func MustGetAs[T any](c *Context, key string) T {
+	val, ok := c.MustGet(key).(T)
+	if !ok {
+		panic(fmt.Sprintf("web: Context 里面 key [%s] 的值是 %T，不是 %T", key, c.keys[key], val))
+	}
+	return val
+}
+
+// Context 返回 Req.Context()，并且带上 Set 保存的值，
+// 可以传给数据库、RPC 之类需要 context.Context 的库，请求取消的时候它们也会退出。
+// 返回的 context.Context 保存的是调用时的值的副本，所以在 handler 返回之后、
+// 在别的 goroutine 里面也可以继续使用；之后再 Set 的值需要重新调用 Context() 才能看到
+func (c *Context) Context() context.Context {
+	if len(c.keys) == 0 {
+		return c.Req.Context()
+	}
+	if c.ctx == nil || c.ctxReq != c.Req {
+		keys := make(map[string]any, len(c.keys))
+		for k, v := range c.keys {
+			keys[k] = v
+		}
+		c.ctx = &valuesCtx{Context: c.Req.Context(), keys: keys}
+		c.ctxReq = c.Req
+	}
+	return c.ctx
+}
+
+// valuesCtx key 是字符串的时候先找 Set 保存的值，找不到再交给 Req.Context()
+type valuesCtx struct {
+	context.Context
+	keys map[string]any
+}
+
+func (v *valuesCtx) Value(key any) any {
+	if k, ok := key.(string); ok {
+		if val, ok := v.keys[k]; ok {
+			return val
+		}
+	}
+	return v.Context.Value(key)
+}
+
+// reset 放回池子之前清空 Context，保留路径参数的容量
+// clone 复制一份给别的 goroutine 使用的 Context，路径参数和 Set 保存的值也会复制，
+// 因为当前的 Context 可能在副本还在使用的时候就被放回池子里复用
+func (c *Context) clone(req *http.Request, resp http.ResponseWriter) *Context {
+	sub := &Context{
+		Req:   req,
+		Resp:  resp,
+		match: Match{Handler: c.match.Handler, Pattern: c.match.Pattern, Params: append([]Param(nil), c.match.Params...)},
+
+		requestID: c.requestID,
+	}
+	for k, v := range c.keys {
+		sub.Set(k, v)
+	}
+	return sub
+}
+
+// merge 把副本里 Set 保存的值和路由查找的结果复制回来，
+// 作为全局的 middleware 时，路由是在副本上查找的
+func (c *Context) merge(sub *Context) {
+	for k, v := range sub.keys {
+		c.Set(k, v)
+	}
+	c.match.Handler, c.match.Pattern = sub.match.Handler, sub.match.Pattern
+	c.match.Params = append(c.match.Params[:0], sub.match.Params...)
+}
+
+func (c *Context) reset() {
+	c.Req = nil
+	c.Resp = nil
+	c.match = Match{Params: c.match.Params[:0]}
+	c.requestID = ""
+	c.ctx, c.ctxReq = nil, nil
+	for k := range c.keys {
+		delete(c.keys, k)
+	}
 }
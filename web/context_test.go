@@ -0,0 +1,96 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+func TestContext_Keys(t *testing.T) {
+	ctx := &Context{}
+	_, ok := ctx.Get("user")
+	assert.False(t, ok)
+	assert.PanicsWithValue(t, "web: Context 里面没有 key [user]", func() {
+		ctx.MustGet("user")
+	})
+
+	ctx.Set("user", int64(123))
+	val, ok := ctx.Get("user")
+	assert.True(t, ok)
+	assert.Equal(t, int64(123), val)
+	assert.Equal(t, int64(123), ctx.MustGet("user"))
+
+	id, ok := GetAs[int64](ctx, "user")
+	assert.True(t, ok)
+	assert.Equal(t, int64(123), id)
+	_, ok = GetAs[string](ctx, "user")
+	assert.False(t, ok)
+	_, ok = GetAs[string](ctx, "missing")
+	assert.False(t, ok)
+	assert.Equal(t, int64(123), MustGetAs[int64](ctx, "user"))
+	assert.PanicsWithValue(t, "web: Context 里面 key [user] 的值是 int64，不是 string", func() {
+		MustGetAs[string](ctx, "user")
+	})
+
+	ctx.reset()
+	_, ok = ctx.Get("user")
+	assert.False(t, ok)
+}
+
+func TestContext_Context(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	reqCtx, cancel := context.WithDeadline(context.WithValue(context.Background(), ctxKey{}, "req"), deadline)
+	ctx := &Context{Req: httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)}
+
+	// 没有 Set 过的时候就是 Req.Context()
+	assert.Equal(t, reqCtx, ctx.Context())
+
+	ctx.Set("user", "why")
+	c := ctx.Context()
+	assert.Same(t, c, ctx.Context())
+	d, ok := c.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, deadline, d)
+	assert.Equal(t, "why", c.Value("user"))
+	assert.Equal(t, "req", c.Value(ctxKey{}))
+	assert.Nil(t, c.Value("missing"))
+
+	// 之后 Set 的值要重新调用 Context() 才能看到
+	ctx.Set("order", 1)
+	assert.Nil(t, c.Value("order"))
+	assert.Equal(t, 1, ctx.Context().Value("order"))
+
+	// 可以作为父 context 使用，Context 被复用之后也不受影响
+	child, childCancel := context.WithCancel(c)
+	defer childCancel()
+	ctx.reset()
+	assert.Equal(t, "why", child.Value("user"))
+	assert.NoError(t, c.Err())
+	cancel()
+	<-c.Done()
+	<-child.Done()
+	assert.Equal(t, context.Canceled, c.Err())
+}
+
+func TestHttpServer_ContextKeys(t *testing.T) {
+	h := NewHTTPServer()
+	h.Use(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			ctx.Set("user", "why")
+			next(ctx)
+		}
+	})
+	h.Get("/", func(ctx *Context) {
+		_, _ = ctx.Resp.Write([]byte(MustGetAs[string](ctx, "user")))
+		_, _ = ctx.Resp.Write([]byte(ctx.Context().Value("user").(string)))
+	})
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "whywhy", recorder.Body.String())
+}
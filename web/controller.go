@@ -0,0 +1,155 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// controllerMethods 按照命名约定注册时识别的 HTTP 方法，顺序决定了匹配的优先级
+var controllerMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions,
+}
+
+var handleFuncType = reflect.TypeOf(HandleFunc(nil))
+
+// Controller 把 ctrl 上签名是 func(*Context) 的导出方法注册为路由，类似 beego 的 Router。
+//
+// 传了 mappings 的时候，按照 mappings 注册，所有方法都注册在 prefix 上，比如：
+//
+//	h.Controller("/user", c, "get:GetUser;post:CreateUser")
+//
+// 多个 HTTP 方法用逗号隔开，* 表示常用的所有 HTTP 方法，比如 "get,post:Handle"、"*:Any"。
+//
+// 没有传 mappings 的时候按照命名约定注册：
+// 方法名就是 HTTP 方法的，比如 Get、Post，注册在 prefix 上；
+// 方法名以 HTTP 方法开头的，剩余的部分转成小写并用 - 连接之后作为 prefix 下面的一段，
+// 比如 GetUserDetail 注册为 GET prefix/user-detail。其余的方法会被忽略。
+//
+// mappings 格式不对、方法不存在或者签名不对的时候 panic
+func (h *HttpServer) Controller(prefix string, ctrl any, mappings ...string) {
+	routes, err := controllerRoutes(prefix, ctrl, mappings)
+	if err != nil {
+		panic(err.Error())
+	}
+	for _, r := range routes {
+		if _, err = h.handle(nil, r.Method, r.Pattern, r.Handler, r.handlerName, nil); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+// controllerRoutes 解析 ctrl 需要注册的路由，只用到 RouteInfo 的 Method、Pattern、Handler 和 handlerName
+func controllerRoutes(prefix string, ctrl any, mappings []string) ([]RouteInfo, error) {
+	val := reflect.ValueOf(ctrl)
+	if !val.IsValid() {
+		return nil, fmt.Errorf("web: controller 不能是 nil")
+	}
+	if len(mappings) == 0 {
+		return conventionRoutes(prefix, val), nil
+	}
+
+	var res []RouteInfo
+	for _, mapping := range mappings {
+		for _, item := range strings.Split(mapping, ";") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			methods, name, ok := strings.Cut(item, ":")
+			if !ok || methods == "" || name == "" {
+				return nil, fmt.Errorf("web: controller 映射 [%s] 格式不对，应该是 get:GetUser 这种形式", item)
+			}
+			fn, fnName, err := controllerMethod(val, strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			for _, method := range strings.Split(methods, ",") {
+				method = strings.ToUpper(strings.TrimSpace(method))
+				if method == "*" {
+					for _, m := range controllerMethods {
+						res = append(res, RouteInfo{Method: m, Pattern: prefix, Handler: fn, handlerName: fnName})
+					}
+					continue
+				}
+				res = append(res, RouteInfo{Method: method, Pattern: prefix, Handler: fn, handlerName: fnName})
+			}
+		}
+	}
+	return res, nil
+}
+
+// controllerMethod 找到名字为 name 的导出方法，签名必须是 func(*Context)，同时返回方法的名字
+func controllerMethod(val reflect.Value, name string) (HandleFunc, string, error) {
+	method, ok := val.Type().MethodByName(name)
+	if !ok {
+		return nil, "", fmt.Errorf("web: %s 没有方法 %s", val.Type(), name)
+	}
+	m := val.Method(method.Index)
+	if !m.Type().ConvertibleTo(handleFuncType) {
+		return nil, "", fmt.Errorf("web: %s.%s 的签名必须是 func(*web.Context)", val.Type(), name)
+	}
+	return m.Convert(handleFuncType).Interface().(HandleFunc), methodName(method), nil
+}
+
+// methodName 返回方法的名字，比如 main.(*UserController).GetUser。
+// 反射得到的 handler 的函数名都是 reflect.methodValueCall，所以要在注册的时候记下来
+func methodName(m reflect.Method) string {
+	fn := runtime.FuncForPC(m.Func.Pointer())
+	if fn == nil {
+		return ""
+	}
+	return fn.Name()
+}
+
+func conventionRoutes(prefix string, val reflect.Value) []RouteInfo {
+	var res []RouteInfo
+	typ := val.Type()
+	for i := 0; i < typ.NumMethod(); i++ {
+		mt := typ.Method(i)
+		name := mt.Name
+		m := val.Method(i)
+		if !m.Type().ConvertibleTo(handleFuncType) {
+			continue
+		}
+		for _, method := range controllerMethods {
+			verb := method[:1] + strings.ToLower(method[1:])
+			rest := strings.TrimPrefix(name, verb)
+			if rest == name {
+				continue
+			}
+			path := prefix
+			if rest != "" {
+				// GetUser 是 GET user，Getter 不是 GET ter
+				if !unicode.IsUpper(rune(rest[0])) {
+					continue
+				}
+				path = strings.TrimSuffix(prefix, "/") + "/" + kebab(rest)
+			}
+			fn := m.Convert(handleFuncType).Interface().(HandleFunc)
+			res = append(res, RouteInfo{Method: method, Pattern: path, Handler: fn, handlerName: methodName(mt)})
+			break
+		}
+	}
+	return res
+}
+
+// kebab UserDetail 转成 user-detail，连续的大写字母当作一个词，比如 UserID 转成 user-id
+func kebab(s string) string {
+	rs := []rune(s)
+	var sb strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (!unicode.IsUpper(rs[i-1]) || i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+				sb.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,143 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// UserController 对应 beego 包里的 UserController
+type UserController struct{}
+
+func (c *UserController) GetUser(ctx *Context) {
+	_, _ = ctx.Resp.Write([]byte("hello, i am whysk8"))
+}
+
+func (c *UserController) CreateUser(ctx *Context) {
+	user := struct{ Name string }{}
+	if err := json.NewDecoder(ctx.Req.Body).Decode(&user); err != nil {
+		_, _ = ctx.Resp.Write([]byte(err.Error()))
+		return
+	}
+	_ = json.NewEncoder(ctx.Resp).Encode(user)
+}
+
+// Name 签名不对，不会被注册
+func (c *UserController) Name() string {
+	return "user"
+}
+
+type OrderController struct{}
+
+func (c *OrderController) Get(ctx *Context) {
+	_, _ = ctx.Resp.Write([]byte("list"))
+}
+
+func (c *OrderController) PostItemID(ctx *Context) {
+	_, _ = ctx.Resp.Write([]byte("item id"))
+}
+
+func (c *OrderController) Getter(ctx *Context) {}
+
+func (c *OrderController) Export(ctx *Context) {}
+
+func TestHttpServer_Controller(t *testing.T) {
+	h := NewHTTPServer()
+	h.Controller("/user", &UserController{}, "get:GetUser;post:CreateUser")
+	h.Controller("/order", &OrderController{})
+
+	var got []string
+	for _, r := range h.Routes() {
+		got = append(got, r.String())
+	}
+	assert.ElementsMatch(t, []string{
+		"GET /user -> geektimeGoClass/web.(*UserController).GetUser",
+		"POST /user -> geektimeGoClass/web.(*UserController).CreateUser",
+		"GET /order -> geektimeGoClass/web.(*OrderController).Get",
+		"POST /order/item-id -> geektimeGoClass/web.(*OrderController).PostItemID",
+	}, got)
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantBody string
+	}{
+		{name: "get user", method: http.MethodGet, path: "/user", wantBody: "hello, i am whysk8"},
+		{name: "create user", method: http.MethodPost, path: "/user", body: `{"Name":"why"}`, wantBody: "{\"Name\":\"why\"}\n"},
+		{name: "order", method: http.MethodGet, path: "/order", wantBody: "list"},
+		{name: "order item", method: http.MethodPost, path: "/order/item-id", wantBody: "item id"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestControllerRoutes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ctrl     any
+		mappings []string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name: "multi methods", ctrl: &UserController{}, mappings: []string{"get, post:GetUser", "put:CreateUser"},
+			want: []string{"GET /user", "POST /user", "PUT /user"},
+		},
+		{
+			name: "any", ctrl: &OrderController{}, mappings: []string{"*:Get"},
+			want: []string{"GET /user", "POST /user", "PUT /user", "PATCH /user", "DELETE /user", "HEAD /user", "OPTIONS /user"},
+		},
+		{name: "nil", wantErr: "web: controller 不能是 nil"},
+		{
+			name: "bad mapping", ctrl: &UserController{}, mappings: []string{"GetUser"},
+			wantErr: "web: controller 映射 [GetUser] 格式不对，应该是 get:GetUser 这种形式",
+		},
+		{
+			name: "missing method", ctrl: &UserController{}, mappings: []string{"get:DeleteUser"},
+			wantErr: "web: *web.UserController 没有方法 DeleteUser",
+		},
+		{
+			name: "bad signature", ctrl: &UserController{}, mappings: []string{"get:Name"},
+			wantErr: "web: *web.UserController.Name 的签名必须是 func(*web.Context)",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			routes, err := controllerRoutes("/user", tc.ctrl, tc.mappings)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			var got []string
+			for _, r := range routes {
+				got = append(got, r.Method+" "+r.Pattern)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestKebab(t *testing.T) {
+	testCases := map[string]string{
+		"User":       "user",
+		"UserDetail": "user-detail",
+		"UserID":     "user-id",
+		"HTTPServer": "http-server",
+		"V2":         "v2",
+	}
+	for in, want := range testCases {
+		assert.Equal(t, want, kebab(in), in)
+	}
+}
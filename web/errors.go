@@ -0,0 +1,66 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// 注册路由时路径格式不正确
+var (
+	ErrEmptyPath          = errors.New("web：路径不能为空字符串")
+	ErrPathNoLeadingSlash = errors.New("web：路径必须以 / 开头")
+	ErrPathTrailingSlash  = errors.New("web：路径不能以 / 结尾")
+	ErrPathDoubleSlash    = errors.New("web：路径不能出现连续的 /")
+)
+
+// ErrConflict 路由冲突。
+// 重复注册同一个路径的时候 Existing 和 New 都是完整的路径；
+// 同一个位置注册了不同名字的参数路径或者正则路径时，它们是冲突的那一段，比如 :id 和 :age
+type ErrConflict struct {
+	Existing string
+	New      string
+}
+
+func (e *ErrConflict) Error() string {
+	if e.Existing == e.New {
+		return fmt.Sprintf("web: 路径冲突，重复注册[%s]", e.New)
+	}
+	return fmt.Sprintf("web: 路径冲突，已注册[%s]，重复注册[%s]", e.Existing, e.New)
+}
+
+// ErrMixedParamKinds 同一个位置上不能同时有参数路径、正则路径和通配符路径
+// Existing 是已经注册的那一段，New 是正在注册的那一段
+type ErrMixedParamKinds struct {
+	Existing string
+	New      string
+}
+
+func (e *ErrMixedParamKinds) Error() string {
+	kind := "参数路径"
+	if e.Existing == "*" {
+		kind = "通配符路径"
+	} else if regPathPattern.MatchString(e.Existing) {
+		kind = "正则路径"
+	}
+	return "web：不允许同时注册路径参数，通配符路径或正则路径，已有" + kind
+}
+
+// ErrBadRegex 正则路径里的正则表达式不合法
+type ErrBadRegex struct {
+	Expr string
+	Err  error
+}
+
+// Error 和 regexp.MustCompile 的 panic 信息保持一致
+func (e *ErrBadRegex) Error() string {
+	expr := strconv.Quote(e.Expr)
+	if strconv.CanBackquote(e.Expr) {
+		expr = "`" + e.Expr + "`"
+	}
+	return "regexp: Compile(" + expr + "): " + e.Err.Error()
+}
+
+func (e *ErrBadRegex) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,120 @@
+// Package ginadapter 把 gin 的 handler 注册到 web.HttpServer 上，方便从 gin 迁移，比如：
+//
+//	ctrl := &UserController{}
+//	h.Get("/user/:id", ginadapter.Wrap(ctrl.GetUser))
+//
+// web 的路径参数会变成 gin.Context.Params，所以 ctx.Param("id") 可以照常使用
+package ginadapter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"geektimeGoClass/web"
+	"github.com/gin-gonic/gin"
+)
+
+// routePrefix 内部路由的前缀，每次 Wrap 在 gin.Engine 上注册一个路由 /.ginadapter/1、/.ginadapter/2……
+// 请求先按照这个路由交给 gin，gin 的 handler 看到的还是原来的请求
+const routePrefix = "/.ginadapter/"
+
+var (
+	// mu 保护 chains 和重新创建 gin.Engine 的过程，处理请求的时候不加锁
+	mu     sync.Mutex
+	chains [][]gin.HandlerFunc
+	// current 存放 *engine，创建之后不再修改，有新的路由的时候整个替换
+	current  atomic.Value
+	hookOnce sync.Once
+)
+
+// engine 注册了前 routes 个 Wrap 的路由的 gin.Engine
+type engine struct {
+	*gin.Engine
+	routes int
+}
+
+type callKey struct{}
+
+// call 一次请求原来的 *http.Request 和 web 的路径参数
+type call struct {
+	req    *http.Request
+	params []web.Param
+}
+
+// Wrap 把 gin 的 handler 链转换成 web.HandleFunc，
+// handlers 按顺序执行，和 gin 里注册在同一个路由上一样，可以调用 ctx.Next 或者 ctx.Abort。
+//
+// ctx.FullPath() 是内部使用的路由，不是 web 注册的路由，需要的话用 ctx.Request 自己判断。
+// 所有的 Wrap 共用一个 gin.Engine，在第一个请求到来的时候创建；
+// Wrap 可以在服务运行期间调用，这之后的第一个请求会重新创建 gin.Engine，
+// 正在处理的请求继续使用旧的，所以不会互相阻塞。gin 在 debug 模式下每次创建都会输出一次警告
+func Wrap(handlers ...gin.HandlerFunc) web.HandleFunc {
+	mu.Lock()
+	chains = append(chains, append([]gin.HandlerFunc{restore}, handlers...))
+	n := len(chains)
+	mu.Unlock()
+	route := routePrefix + strconv.Itoa(n)
+
+	return func(ctx *web.Context) {
+		e, _ := current.Load().(*engine)
+		if e == nil || e.routes < n {
+			e = rebuild()
+		}
+		c := &call{req: ctx.Req, params: ctx.PathParams()}
+		req := ctx.Req.WithContext(context.WithValue(ctx.Req.Context(), callKey{}, c))
+		req.Method = http.MethodGet
+		req.URL = &url.URL{Path: route}
+		e.ServeHTTP(ctx.Resp, req)
+	}
+}
+
+// rebuild 用所有 Wrap 的路由创建新的 gin.Engine，已经有别的请求创建好了就直接使用
+func rebuild() *engine {
+	mu.Lock()
+	defer mu.Unlock()
+	if e, _ := current.Load().(*engine); e != nil && e.routes == len(chains) {
+		return e
+	}
+	hookOnce.Do(hideRoutes)
+	g := gin.New()
+	// 路由是内部生成的，不需要 gin 来处理路径末尾的 /
+	g.RedirectTrailingSlash = false
+	for i, chain := range chains {
+		g.GET(routePrefix+strconv.Itoa(i+1), chain...)
+	}
+	e := &engine{Engine: g, routes: len(chains)}
+	current.Store(e)
+	return e
+}
+
+// hideRoutes debug 模式下 gin 会输出注册的每一个路由，内部的路由对用户没有意义，不输出。
+// 其它的路由还是按照 gin 默认的格式输出，或者交给之前设置的 gin.DebugPrintRouteFunc
+func hideRoutes() {
+	prev := gin.DebugPrintRouteFunc
+	gin.DebugPrintRouteFunc = func(httpMethod, absolutePath, handlerName string, nuHandlers int) {
+		if strings.HasPrefix(absolutePath, routePrefix) {
+			return
+		}
+		if prev != nil {
+			prev(httpMethod, absolutePath, handlerName, nuHandlers)
+			return
+		}
+		fmt.Fprintf(gin.DefaultWriter, "[GIN-debug] %-6s %-25s --> %s (%d handlers)\n", httpMethod, absolutePath, handlerName, nuHandlers)
+	}
+}
+
+// restore 在 gin 的 handler 之前执行，换回原来的请求，并且设置路径参数
+func restore(c *gin.Context) {
+	cl := c.Request.Context().Value(callKey{}).(*call)
+	c.Request = cl.req
+	c.Params = c.Params[:0]
+	for _, p := range cl.params {
+		c.Params = append(c.Params, gin.Param{Key: p.Key, Value: p.Value})
+	}
+}
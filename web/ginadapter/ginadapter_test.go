@@ -0,0 +1,141 @@
+package ginadapter
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"geektimeGoClass/web"
+	demo "geektimeGoClass/web/gin"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := web.NewHTTPServer()
+	ctrl := &demo.UserController{}
+	h.Get("/user", Wrap(ctrl.GetUser))
+	h.Get("/user/:id/order/:order", Wrap(func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "%s %s", ctx.Param("id"), ctx.Param("order"))
+	}))
+	h.Get("/auth", Wrap(
+		func(ctx *gin.Context) {
+			if ctx.GetHeader("Token") == "" {
+				ctx.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			ctx.Set("user", "why")
+			ctx.Next()
+		},
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{"user": ctx.GetString("user")})
+		},
+	))
+	h.Get("/empty", Wrap(func(ctx *gin.Context) {}))
+	h.Get("/status", Wrap(func(ctx *gin.Context) {
+		ctx.Status(http.StatusNotFound)
+	}))
+	h.Post("/request/:id", Wrap(func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "%s %s %s", ctx.Request.Method, ctx.Request.URL.Path, ctx.Query("a"))
+	}))
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		header   http.Header
+		wantCode int
+		wantBody string
+	}{
+		{name: "controller", path: "/user", wantCode: http.StatusOK, wantBody: "hello, i am whysk8"},
+		{name: "params", path: "/user/123/order/456", wantCode: http.StatusOK, wantBody: "123 456"},
+		{name: "abort", path: "/auth", wantCode: http.StatusUnauthorized},
+		{
+			name: "next", path: "/auth", header: http.Header{"Token": {"abc"}},
+			wantCode: http.StatusOK, wantBody: `{"user":"why"}`,
+		},
+		{name: "no response", path: "/empty", wantCode: http.StatusOK},
+		// 只设置了状态码的时候不能有 gin 的 404 page not found
+		{name: "status only", path: "/status", wantCode: http.StatusNotFound},
+		{name: "request", method: http.MethodPost, path: "/request/123?a=b", wantCode: http.StatusOK, wantBody: "POST /request/123 b"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tc.path, nil)
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestWrap_DebugMode(t *testing.T) {
+	mode, writer := gin.Mode(), gin.DefaultWriter
+	defer func() {
+		gin.SetMode(mode)
+		gin.DefaultWriter = writer
+	}()
+	buf := &bytes.Buffer{}
+	gin.DefaultWriter = buf
+	gin.SetMode(gin.DebugMode)
+	current.Store((*engine)(nil))
+
+	// 启动之前的 Wrap 共用一个 gin.Engine，debug 模式的警告只会输出一次
+	h := web.NewHTTPServer()
+	h.Get("/a", Wrap(func(ctx *gin.Context) { ctx.String(http.StatusOK, "a") }))
+	h.Get("/b", Wrap(func(ctx *gin.Context) { ctx.String(http.StatusOK, "b") }))
+	for _, path := range []string{"/a", "/b", "/a"} {
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, path[1:], recorder.Body.String())
+	}
+	assert.Equal(t, 1, strings.Count(buf.String(), "Running in \"debug\" mode"))
+	// 内部的路由不输出，用户自己的 gin 路由照常输出
+	assert.NotContains(t, buf.String(), routePrefix)
+	gin.New().GET("/user", func(ctx *gin.Context) {})
+	assert.Contains(t, buf.String(), "[GIN-debug] GET    /user")
+}
+
+func TestWrap_Runtime(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := web.NewHTTPServer()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h.Get("/slow", Wrap(func(ctx *gin.Context) {
+		close(started)
+		<-release
+		ctx.String(http.StatusOK, "slow")
+	}))
+	h.Get("/fast", Wrap(func(ctx *gin.Context) { ctx.String(http.StatusOK, "fast") }))
+
+	slow := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTP(slow, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		close(done)
+	}()
+	<-started
+
+	// 慢请求处理期间注册新的路由，其它的请求不会被阻塞
+	h.Get("/new", Wrap(func(ctx *gin.Context) { ctx.String(http.StatusOK, "new") }))
+	for _, path := range []string{"/fast", "/new"} {
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, path[1:], recorder.Body.String())
+	}
+
+	close(release)
+	<-done
+	assert.Equal(t, "slow", slow.Body.String())
+}
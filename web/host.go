@@ -0,0 +1,168 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// HostGroup 只处理某个域名的路由
+type HostGroup struct {
+	server *HttpServer
+	host   *hostRoutes
+}
+
+// hostRoutes 一个域名自己的路由树
+type hostRoutes struct {
+	pattern string
+	// 按 . 切分之后的域名，:tenant 这种表示参数
+	labels   []string
+	hasParam bool
+	router   Router
+}
+
+// Host 返回域名 pattern 的路由分组，pattern 可以是确定的域名，比如 api.example.com；
+// 也可以带参数，比如 :tenant.example.com，参数和路径参数一样通过 Context.PathValue 获取。
+// 查找路由的时候优先匹配确定的域名，然后按照注册的顺序匹配带参数的域名；
+// 域名没有匹配上或者这个域名下面没有对应的路由时，使用没有指定域名的默认路由。
+// 分组内部使用 NewTreeRouter。
+// pattern 不能为空，不能有空的部分，比如 api..example.com，也不能带端口，否则 panic
+func (h *HttpServer) Host(pattern string) *HostGroup {
+	if err := validHost(pattern); err != nil {
+		panic(err.Error())
+	}
+	pattern = strings.ToLower(pattern)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old := h.loadHosts()
+	for _, hr := range old {
+		if hr.pattern == pattern {
+			return &HostGroup{server: h, host: hr}
+		}
+	}
+	hr := &hostRoutes{
+		pattern:  pattern,
+		labels:   strings.Split(pattern, "."),
+		hasParam: strings.Contains(pattern, ":"),
+		router:   NewTreeRouter(),
+	}
+	hosts := make([]*hostRoutes, len(old), len(old)+1)
+	copy(hosts, old)
+	h.hosts.Store(append(hosts, hr))
+	return &HostGroup{server: h, host: hr}
+}
+
+// validHost 检查域名的 pattern，查找路由的时候会去掉请求里的端口，所以带端口的域名永远不会命中
+func validHost(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("web: 域名不能为空")
+	}
+	for _, label := range strings.Split(pattern, ".") {
+		switch {
+		case label == "":
+			return fmt.Errorf("web: 域名 [%s] 不能有空的部分", pattern)
+		case label == ":":
+			return fmt.Errorf("web: 域名 [%s] 的参数必须有名字", pattern)
+		case strings.ContainsAny(label, "[]"):
+			return fmt.Errorf("web: 域名 [%s] 不能是 IPv6 地址", pattern)
+		case strings.Contains(label[1:], ":"):
+			return fmt.Errorf("web: 域名 [%s] 不能带端口", pattern)
+		}
+	}
+	return nil
+}
+
+func (h *HttpServer) loadHosts() []*hostRoutes {
+	hosts, _ := h.hosts.Load().([]*hostRoutes)
+	return hosts
+}
+
+func (g *HostGroup) Get(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return g.Handle(http.MethodGet, path, handleFunc, mdls...)
+}
+
+func (g *HostGroup) Post(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return g.Handle(http.MethodPost, path, handleFunc, mdls...)
+}
+
+func (g *HostGroup) Handle(method string, path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	r, err := g.TryHandle(method, path, handleFunc, mdls...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return r
+}
+
+// TryHandle 注册失败的时候返回错误，见 HttpServer.TryHandle
+func (g *HostGroup) TryHandle(method string, path string, handleFunc HandleFunc, mdls ...Middleware) (*Route, error) {
+	return g.server.handle(g.host, method, path, handleFunc, "", mdls)
+}
+
+// RemoveRoute 删除这个域名下面的路由，路由的名字也会一起删除
+func (g *HostGroup) RemoveRoute(method string, path string) bool {
+	if !removeRoute(g.host.router, method, path) {
+		return false
+	}
+	g.server.mu.Lock()
+	g.server.removeNames(g.host, method, path)
+	g.server.mu.Unlock()
+	return true
+}
+
+// findHost 找到 host 对应的路由树，域名里的参数追加到 params 后面
+func (h *HttpServer) findHost(host string, params []Param) (*hostRoutes, []Param) {
+	hosts := h.loadHosts()
+	if len(hosts) == 0 {
+		return nil, params
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	// 确定的域名优先
+	for _, hr := range hosts {
+		if !hr.hasParam && strings.EqualFold(hr.pattern, host) {
+			return hr, params
+		}
+	}
+	for _, hr := range hosts {
+		if !hr.hasParam {
+			continue
+		}
+		if res, ok := hr.match(host, params); ok {
+			return hr, res
+		}
+	}
+	return nil, params
+}
+
+// match 按 . 逐段比较，不需要切分 host
+func (hr *hostRoutes) match(host string, params []Param) ([]Param, bool) {
+	res := params
+	for i, label := range hr.labels {
+		end := strings.IndexByte(host, '.')
+		if i == len(hr.labels)-1 {
+			if end >= 0 {
+				return params, false
+			}
+			end = len(host)
+		} else if end < 0 {
+			return params, false
+		}
+		seg := host[:end]
+		if seg == "" {
+			return params, false
+		}
+		if label[0] == ':' {
+			res = append(res, Param{Key: label[1:], Value: seg})
+		} else if !strings.EqualFold(label, seg) {
+			return params, false
+		}
+		if end < len(host) {
+			host = host[end+1:]
+		} else {
+			host = ""
+		}
+	}
+	return res, true
+}
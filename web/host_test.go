@@ -0,0 +1,95 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpServer_Host(t *testing.T) {
+	h := NewHTTPServer()
+	write := func(s string) HandleFunc {
+		return func(ctx *Context) {
+			tenant, _ := ctx.PathValue("tenant")
+			id, _ := ctx.PathValue("id")
+			_, _ = ctx.Resp.Write([]byte(strings.Join([]string{s, tenant, id}, " ")))
+		}
+	}
+	h.Get("/user/:id", write("default"))
+	h.Get("/home", write("default home"))
+	h.Host("api.example.com").Get("/user/:id", write("api"))
+	h.Host(":tenant.example.com").Get("/user/:id", write("tenant"))
+	h.Host(":tenant.example.com").Get("/order", write("tenant order"))
+
+	testCases := []struct {
+		name     string
+		host     string
+		path     string
+		wantBody string
+	}{
+		{name: "exact first", host: "api.example.com", path: "/user/1", wantBody: "api  1"},
+		{name: "exact with port", host: "API.example.com:8081", path: "/user/1", wantBody: "api  1"},
+		{name: "param", host: "abc.example.com", path: "/user/1", wantBody: "tenant abc 1"},
+		{name: "same group", host: "abc.example.com", path: "/order", wantBody: "tenant order abc "},
+		// 域名匹配上了，但是这个域名下面没有这个路由
+		{name: "fallback", host: "abc.example.com", path: "/home", wantBody: "default home  "},
+		{name: "too many labels", host: "a.b.example.com", path: "/user/1", wantBody: "default  1"},
+		{name: "other domain", host: "abc.example.org", path: "/user/1", wantBody: "default  1"},
+		{name: "no host", path: "/user/1", wantBody: "default  1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Host = tc.host
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+
+	routes := h.Routes()
+	require.Len(t, routes, 5)
+	assert.Equal(t, ":tenant.example.com", routes[4].Host)
+	assert.Equal(t, "/user/:id", routes[4].Pattern)
+}
+
+func TestHttpServer_Host_Invalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pattern string
+		wantErr string
+	}{
+		{name: "empty", pattern: "", wantErr: "web: 域名不能为空"},
+		{name: "empty label", pattern: "api..example.com", wantErr: "web: 域名 [api..example.com] 不能有空的部分"},
+		{name: "trailing dot", pattern: "api.example.com.", wantErr: "web: 域名 [api.example.com.] 不能有空的部分"},
+		{name: "empty param", pattern: ":.example.com", wantErr: "web: 域名 [:.example.com] 的参数必须有名字"},
+		{name: "port", pattern: "api.example.com:8080", wantErr: "web: 域名 [api.example.com:8080] 不能带端口"},
+		{name: "param port", pattern: ":tenant.example.com:8080", wantErr: "web: 域名 [:tenant.example.com:8080] 不能带端口"},
+		{name: "ipv6", pattern: "[::1]", wantErr: "web: 域名 [[::1]] 不能是 IPv6 地址"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHTTPServer()
+			assert.PanicsWithValue(t, tc.wantErr, func() {
+				h.Host(tc.pattern)
+			})
+			assert.Empty(t, h.loadHosts())
+		})
+	}
+}
+
+func TestHostRoutes_Match(t *testing.T) {
+	hr := &hostRoutes{labels: strings.Split(":tenant.:region.example.com", ".")}
+	params, ok := hr.match("abc.us.example.com", nil)
+	assert.True(t, ok)
+	assert.Equal(t, []Param{{Key: "tenant", Value: "abc"}, {Key: "region", Value: "us"}}, params)
+
+	for _, host := range []string{"abc.example.com", ".us.example.com", "abc.us.example.com.cn", ""} {
+		_, ok = hr.match(host, nil)
+		assert.False(t, ok, host)
+	}
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Middleware 包装 HandleFunc，在业务逻辑前后做一些通用的事情，比如鉴权、日志
+type Middleware func(next HandleFunc) HandleFunc
+
+// chain 把 mdls 套在 fn 外面，mdls[0] 在最外层，也就是最先执行
+func chain(fn HandleFunc, mdls ...Middleware) HandleFunc {
+	for i := len(mdls) - 1; i >= 0; i-- {
+		fn = mdls[i](fn)
+	}
+	return fn
+}
+
+type contextKey struct{}
+
+// httpCall 一次经过 HTTPMiddleware 的调用。
+// mw 可能在别的 goroutine 里面调用下一个 handler，比如 http.TimeoutHandler，
+// 这个时候 mw 返回之后下一个 handler 可能还在执行，所以它只能使用 Context 的副本
+type httpCall struct {
+	ctx *Context
+
+	mu sync.Mutex
+	// returned 表示 mw 已经返回了，done 表示下一个 handler 在这之前就执行完了
+	returned bool
+	done     bool
+}
+
+// HTTPMiddleware 把 func(http.Handler) http.Handler 形式的 middleware 转换成 Middleware，
+// 这样可以直接使用 gzip、CORS、鉴权之类的第三方库。
+// 下一个 handler 拿到的是 Context 的副本，它的 Req 和 Resp 是 mw 传过来的请求和 ResponseWriter，
+// 路径参数等其它的字段和原来的一样。mw 返回的时候，如果下一个 handler 已经执行完了，
+// Set 保存的值和路由查找的结果会复制回原来的 Context，否则丢弃；原来的 Req 和 Resp 保持不变
+func HTTPMiddleware(mw func(http.Handler) http.Handler) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			call, ok := r.Context().Value(contextKey{}).(*httpCall)
+			if !ok {
+				// mw 丢掉了原来的 context，没有办法继续
+				panic("web: HTTPMiddleware 传给下一个 handler 的请求里面没有 *web.Context")
+			}
+			sub := call.ctx
+			sub.Req = r
+			sub.Resp = w
+			next(sub)
+			call.mu.Lock()
+			call.done = !call.returned
+			call.mu.Unlock()
+		}))
+		return func(ctx *Context) {
+			call := &httpCall{ctx: ctx.clone(ctx.Req, ctx.Resp)}
+			h.ServeHTTP(ctx.Resp, ctx.Req.WithContext(context.WithValue(ctx.Req.Context(), contextKey{}, call)))
+			call.mu.Lock()
+			call.returned = true
+			done := call.done
+			call.mu.Unlock()
+			if done {
+				ctx.merge(call.ctx)
+			}
+		}
+	}
+}
+
+// FromRequest 返回经过 HTTPMiddleware 的请求对应的 Context，也就是下一个 handler 使用的副本
+func FromRequest(r *http.Request) (*Context, bool) {
+	call, ok := r.Context().Value(contextKey{}).(*httpCall)
+	if !ok {
+		return nil, false
+	}
+	return call.ctx, true
+}
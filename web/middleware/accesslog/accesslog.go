@@ -0,0 +1,182 @@
+// Package accesslog 访问日志，比如：
+//
+//	h.Use(accesslog.New(accesslog.JSONSink(os.Stdout), accesslog.SkipPaths("/health")))
+//
+// 日志里面记录的是命中的路由，比如 /user/:id，而不是请求的原始路径，避免日志的维度失控
+package accesslog
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"geektimeGoClass/web"
+)
+
+// Entry 一条访问日志
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	// Route 命中的路由，没有命中的时候是空字符串
+	Route     string            `json:"route"`
+	Params    map[string]string `json:"params,omitempty"`
+	Status    int               `json:"status"`
+	Bytes     int               `json:"bytes"`
+	Latency   time.Duration     `json:"latency"`
+	RemoteIP  string            `json:"remote_ip"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// Sink 输出访问日志
+type Sink interface {
+	Log(e *Entry)
+}
+
+type SinkFunc func(e *Entry)
+
+func (f SinkFunc) Log(e *Entry) {
+	f(e)
+}
+
+// JSONSink 每条日志输出一行 JSON，Latency 的单位是纳秒
+func JSONSink(w io.Writer) Sink {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return SinkFunc(func(e *Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = enc.Encode(e)
+	})
+}
+
+// Logger 结构化的日志，*slog.Logger 就实现了这个接口
+type Logger interface {
+	Info(msg string, args ...any)
+}
+
+// LoggerSink 把访问日志交给结构化的日志，消息是 access，字段是成对的 key 和 value
+func LoggerSink(l Logger) Sink {
+	return SinkFunc(func(e *Entry) {
+		args := []any{
+			"method", e.Method,
+			"route", e.Route,
+			"status", e.Status,
+			"bytes", e.Bytes,
+			"latency", e.Latency,
+			"remote_ip", e.RemoteIP,
+		}
+		if len(e.Params) > 0 {
+			args = append(args, "params", e.Params)
+		}
+		if e.RequestID != "" {
+			args = append(args, "request_id", e.RequestID)
+		}
+		l.Info("access", args...)
+	})
+}
+
+// Option 访问日志的选项
+type Option func(b *builder)
+
+// Skip fn 返回 true 的请求不记录，fn 在请求处理完之后调用，所以可以根据 ctx.Route() 判断
+func Skip(fn func(ctx *web.Context) bool) Option {
+	return func(b *builder) {
+		b.skips = append(b.skips, fn)
+	}
+}
+
+// SkipPaths 不记录这些路由，比如健康检查，和 ctx.Route() 比较
+func SkipPaths(routes ...string) Option {
+	set := make(map[string]struct{}, len(routes))
+	for _, r := range routes {
+		set[r] = struct{}{}
+	}
+	return Skip(func(ctx *web.Context) bool {
+		_, ok := set[ctx.Route()]
+		return ok
+	})
+}
+
+// Sample 状态码小于 400 的请求每 n 个只记录一个，出错的请求总是记录
+func Sample(n uint64) Option {
+	return func(b *builder) {
+		b.sample = n
+	}
+}
+
+// RequestID 设置怎么取得请求 ID，默认使用 ctx.RequestID()
+func RequestID(fn func(ctx *web.Context) string) Option {
+	return func(b *builder) {
+		b.requestID = fn
+	}
+}
+
+type builder struct {
+	// count 用于原子操作，放在第一个字段保证 32 位平台上也是 64 位对齐的
+	count     uint64
+	sink      Sink
+	skips     []func(ctx *web.Context) bool
+	sample    uint64
+	requestID func(ctx *web.Context) string
+	now       func() time.Time
+}
+
+// New 创建访问日志的 middleware，通常用 HttpServer.Use 注册在最外层
+func New(sink Sink, opts ...Option) web.Middleware {
+	b := &builder{
+		sink:      sink,
+		requestID: (*web.Context).RequestID,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			start := b.now()
+			rec := web.Record(ctx)
+			next(ctx)
+			b.log(ctx, rec, start)
+		}
+	}
+}
+
+func (b *builder) log(ctx *web.Context, rec *web.ResponseRecorder, start time.Time) {
+	for _, skip := range b.skips {
+		if skip(ctx) {
+			return
+		}
+	}
+	status := rec.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if b.sample > 1 && status < 400 && atomic.AddUint64(&b.count, 1)%b.sample != 1 {
+		return
+	}
+
+	e := &Entry{
+		Time:      start,
+		Method:    ctx.Req.Method,
+		Route:     ctx.Route(),
+		Status:    status,
+		Bytes:     rec.Bytes(),
+		Latency:   b.now().Sub(start),
+		RemoteIP:  ctx.Req.RemoteAddr,
+		RequestID: b.requestID(ctx),
+	}
+	if host, _, err := net.SplitHostPort(e.RemoteIP); err == nil {
+		e.RemoteIP = host
+	}
+	if params := ctx.PathParams(); len(params) > 0 {
+		e.Params = make(map[string]string, len(params))
+		for _, p := range params {
+			e.Params[p.Key] = p.Value
+		}
+	}
+	b.sink.Log(e)
+}
@@ -0,0 +1,127 @@
+package accesslog
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"geektimeGoClass/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(mdl web.Middleware) *web.HttpServer {
+	h := web.NewHTTPServer()
+	h.Use(mdl)
+	h.Get("/user/:id", func(ctx *web.Context) {
+		_, _ = ctx.Resp.Write([]byte("hello"))
+	})
+	h.Get("/health", func(ctx *web.Context) {})
+	h.Post("/fail", func(ctx *web.Context) {
+		ctx.Resp.WriteHeader(http.StatusInternalServerError)
+	})
+	return h
+}
+
+func serve(h *web.HttpServer, method, path string) {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Request-Id", "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+// fixedClock 每次调用前进 10ms
+func fixedClock() func() time.Time {
+	now := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(10 * time.Millisecond)
+		return now
+	}
+}
+
+func TestNew(t *testing.T) {
+	var entries []*Entry
+	clock := fixedClock()
+	h := newServer(New(SinkFunc(func(e *Entry) {
+		entries = append(entries, e)
+	}), SkipPaths("/health"), func(b *builder) { b.now = clock }))
+
+	serve(h, http.MethodGet, "/user/123")
+	serve(h, http.MethodGet, "/health")
+	serve(h, http.MethodPost, "/fail")
+	serve(h, http.MethodGet, "/missing")
+
+	start := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []*Entry{
+		{
+			Time: start.Add(10 * time.Millisecond), Method: http.MethodGet, Route: "/user/:id",
+			Params: map[string]string{"id": "123"}, Status: http.StatusOK, Bytes: 5,
+			Latency: 10 * time.Millisecond, RemoteIP: "10.0.0.1", RequestID: "req-1",
+		},
+		{
+			// /health 调用了一次 now，没有记录
+			Time: start.Add(40 * time.Millisecond), Method: http.MethodPost, Route: "/fail",
+			Status: http.StatusInternalServerError, Latency: 10 * time.Millisecond,
+			RemoteIP: "10.0.0.1", RequestID: "req-1",
+		},
+		{
+			Time: start.Add(60 * time.Millisecond), Method: http.MethodGet,
+			Status: http.StatusNotFound, Bytes: 9, Latency: 10 * time.Millisecond,
+			RemoteIP: "10.0.0.1", RequestID: "req-1",
+		},
+	}, entries)
+}
+
+func TestSample(t *testing.T) {
+	var routes []string
+	h := newServer(New(SinkFunc(func(e *Entry) {
+		routes = append(routes, e.Route)
+	}), Sample(3), RequestID(func(ctx *web.Context) string { return "" })))
+	for i := 0; i < 5; i++ {
+		serve(h, http.MethodGet, "/user/123")
+		serve(h, http.MethodPost, "/fail")
+	}
+	// 成功的请求每 3 个记录一个，失败的请求全部记录
+	assert.Equal(t, []string{"/user/:id", "/fail", "/fail", "/fail", "/user/:id", "/fail", "/fail"}, routes)
+}
+
+func TestJSONSink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sink := JSONSink(buf)
+	sink.Log(&Entry{
+		Time: time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC), Method: http.MethodGet, Route: "/user/:id",
+		Params: map[string]string{"id": "123"}, Status: 200, Bytes: 5, Latency: time.Millisecond,
+		RemoteIP: "10.0.0.1",
+	})
+	assert.Equal(t, `{"time":"2022-08-01T00:00:00Z","method":"GET","route":"/user/:id","params":{"id":"123"},`+
+		`"status":200,"bytes":5,"latency":1000000,"remote_ip":"10.0.0.1"}`+"\n", buf.String())
+}
+
+type mockLogger struct {
+	msg  string
+	args []any
+}
+
+func (l *mockLogger) Info(msg string, args ...any) {
+	l.msg = msg
+	l.args = args
+}
+
+func TestLoggerSink(t *testing.T) {
+	l := &mockLogger{}
+	h := newServer(New(LoggerSink(l)))
+	serve(h, http.MethodGet, "/user/123")
+	require.Equal(t, "access", l.msg)
+	require.Len(t, l.args, 16)
+	assert.Equal(t, []any{
+		"method", "GET", "route", "/user/:id", "status", 200, "bytes", 5,
+	}, l.args[:8])
+	assert.Equal(t, []any{
+		"remote_ip", "10.0.0.1", "params", map[string]string{"id": "123"}, "request_id", "req-1",
+	}, l.args[10:])
+	assert.Equal(t, "latency", l.args[8])
+	assert.IsType(t, time.Duration(0), l.args[9], fmt.Sprint(l.args[9]))
+}
@@ -0,0 +1,198 @@
+// Package cors 跨域资源共享，比如：
+//
+//	h.Use(cors.New(h, cors.AllowOrigins("https://*.example.com"), cors.AllowCredentials()))
+//
+// 预检请求由 middleware 直接回应，允许的 HTTP 方法默认就是这个路径上注册了的方法，
+// 见 web.HttpServer.Methods，不需要再写一遍
+package cors
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"geektimeGoClass/web"
+)
+
+// Option CORS 的选项
+type Option func(b *builder)
+
+// AllowOrigins 允许的来源，可以是确定的来源，比如 https://example.com，
+// 也可以包含一个 *，比如 https://*.example.com；只有一个 * 表示允许所有的来源
+func AllowOrigins(origins ...string) Option {
+	return func(b *builder) {
+		for _, o := range origins {
+			if o == "*" {
+				b.allowAll = true
+				continue
+			}
+			b.origins = append(b.origins, strings.ToLower(o))
+		}
+	}
+}
+
+// AllowOriginRegexp 用正则表达式判断来源
+func AllowOriginRegexp(exprs ...*regexp.Regexp) Option {
+	return func(b *builder) {
+		for _, expr := range exprs {
+			b.originFuncs = append(b.originFuncs, expr.MatchString)
+		}
+	}
+}
+
+// AllowOriginFunc fn 返回 true 的来源是允许的
+func AllowOriginFunc(fn func(origin string) bool) Option {
+	return func(b *builder) {
+		b.originFuncs = append(b.originFuncs, fn)
+	}
+}
+
+// AllowMethods 预检请求返回的 HTTP 方法，默认是这个路径上注册了的方法
+func AllowMethods(methods ...string) Option {
+	return func(b *builder) {
+		b.methods = strings.Join(methods, ", ")
+	}
+}
+
+// AllowHeaders 预检请求返回的请求头，默认是预检请求里面 Access-Control-Request-Headers 的值
+func AllowHeaders(headers ...string) Option {
+	return func(b *builder) {
+		b.headers = strings.Join(headers, ", ")
+	}
+}
+
+// ExposeHeaders 允许浏览器读取的响应头
+func ExposeHeaders(headers ...string) Option {
+	return func(b *builder) {
+		b.exposeHeaders = strings.Join(headers, ", ")
+	}
+}
+
+// AllowCredentials 允许携带 cookie 等凭证，返回的 Access-Control-Allow-Origin 是请求里的 Origin。
+// 不能和 AllowOrigins("*") 一起使用，否则任何网站都能带着用户的凭证访问，
+// 确实需要的话用 AllowOriginFunc 自己判断来源
+func AllowCredentials() Option {
+	return func(b *builder) {
+		b.credentials = true
+	}
+}
+
+// MaxAge 预检请求的结果可以缓存多久
+func MaxAge(d time.Duration) Option {
+	return func(b *builder) {
+		b.maxAge = strconv.Itoa(int(d / time.Second))
+	}
+}
+
+type builder struct {
+	server        *web.HttpServer
+	allowAll      bool
+	origins       []string
+	originFuncs   []func(origin string) bool
+	methods       string
+	headers       string
+	exposeHeaders string
+	credentials   bool
+	maxAge        string
+}
+
+// New 创建 CORS 的 middleware，h 用来查找路径上注册了的 HTTP 方法。
+// 需要用 HttpServer.Use 注册，因为预检请求的 OPTIONS 方法一般没有注册路由。
+// 预检请求的路径上没有任何路由的时候交给后面处理，也就是返回 404；
+// 来源不允许的请求不会带上任何 CORS 的响应头，由浏览器拒绝。
+// 同时使用 AllowOrigins("*") 和 AllowCredentials 会 panic
+func New(h *web.HttpServer, opts ...Option) web.Middleware {
+	b := &builder{server: h}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.allowAll && b.credentials {
+		panic("cors: AllowOrigins(\"*\") 不能和 AllowCredentials 一起使用，请用 AllowOriginFunc 明确判断来源")
+	}
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			origin := ctx.Req.Header.Get("Origin")
+			header := ctx.Resp.Header()
+			header.Add("Vary", "Origin")
+			preflight := ctx.Req.Method == http.MethodOptions && ctx.Req.Header.Get("Access-Control-Request-Method") != ""
+			if !preflight {
+				if origin != "" && b.allowed(origin) {
+					b.allowOrigin(header, origin)
+					if b.exposeHeaders != "" {
+						header.Set("Access-Control-Expose-Headers", b.exposeHeaders)
+					}
+				}
+				next(ctx)
+				return
+			}
+
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			methods := b.methods
+			if methods == "" {
+				registered := b.server.Methods(ctx.Req)
+				if len(registered) == 0 {
+					next(ctx)
+					return
+				}
+				methods = strings.Join(registered, ", ")
+			}
+			if origin != "" && b.allowed(origin) {
+				b.allowOrigin(header, origin)
+				header.Set("Access-Control-Allow-Methods", methods)
+				headers := b.headers
+				if headers == "" {
+					headers = ctx.Req.Header.Get("Access-Control-Request-Headers")
+				}
+				if headers != "" {
+					header.Set("Access-Control-Allow-Headers", headers)
+				}
+				if b.maxAge != "" {
+					header.Set("Access-Control-Max-Age", b.maxAge)
+				}
+			}
+			ctx.Resp.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+func (b *builder) allowOrigin(header http.Header, origin string) {
+	if b.allowAll {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if b.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (b *builder) allowed(origin string) bool {
+	if b.allowAll {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	for _, o := range b.origins {
+		if matchOrigin(o, lower) {
+			return true
+		}
+	}
+	for _, fn := range b.originFuncs {
+		if fn(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin pattern 里面最多有一个 *，可以匹配任意非空的内容
+func matchOrigin(pattern string, origin string) bool {
+	prefix, suffix, ok := strings.Cut(pattern, "*")
+	if !ok {
+		return pattern == origin
+	}
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
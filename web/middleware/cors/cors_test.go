@@ -0,0 +1,186 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"geektimeGoClass/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func newServer(opts ...Option) *web.HttpServer {
+	h := web.NewHTTPServer()
+	h.Use(New(h, opts...))
+	h.Get("/user/:id", func(ctx *web.Context) {
+		_, _ = ctx.Resp.Write([]byte("user"))
+	})
+	h.Post("/user/:id", func(ctx *web.Context) {})
+	h.Handle(http.MethodDelete, "/user/:id", func(ctx *web.Context) {})
+	return h
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name       string
+		opts       []Option
+		method     string
+		path       string
+		header     http.Header
+		wantCode   int
+		wantBody   string
+		wantHeader http.Header
+	}{
+		{
+			name: "no origin", opts: []Option{AllowOrigins("*")},
+			method: http.MethodGet, path: "/user/1",
+			wantCode: http.StatusOK, wantBody: "user",
+			wantHeader: http.Header{"Vary": {"Origin"}},
+		},
+		{
+			name: "allow all", opts: []Option{AllowOrigins("*"), ExposeHeaders("X-Request-ID")},
+			method: http.MethodGet, path: "/user/1",
+			header:   http.Header{"Origin": {"https://a.com"}},
+			wantCode: http.StatusOK, wantBody: "user",
+			wantHeader: http.Header{
+				"Vary":                          {"Origin"},
+				"Access-Control-Allow-Origin":   {"*"},
+				"Access-Control-Expose-Headers": {"X-Request-ID"},
+			},
+		},
+		{
+			name: "credentials", opts: []Option{AllowOrigins("https://a.com"), AllowCredentials()},
+			method: http.MethodGet, path: "/user/1",
+			header:   http.Header{"Origin": {"https://a.com"}},
+			wantCode: http.StatusOK, wantBody: "user",
+			wantHeader: http.Header{
+				"Vary":                             {"Origin"},
+				"Access-Control-Allow-Origin":      {"https://a.com"},
+				"Access-Control-Allow-Credentials": {"true"},
+			},
+		},
+		{
+			name: "wildcard", opts: []Option{AllowOrigins("https://*.example.com")},
+			method: http.MethodGet, path: "/user/1",
+			header:   http.Header{"Origin": {"https://API.example.com"}},
+			wantCode: http.StatusOK, wantBody: "user",
+			wantHeader: http.Header{
+				"Vary":                        {"Origin"},
+				"Access-Control-Allow-Origin": {"https://API.example.com"},
+			},
+		},
+		{
+			name: "wildcard not match", opts: []Option{AllowOrigins("https://*.example.com")},
+			method: http.MethodGet, path: "/user/1",
+			header:   http.Header{"Origin": {"https://example.com"}},
+			wantCode: http.StatusOK, wantBody: "user",
+			wantHeader: http.Header{"Vary": {"Origin"}},
+		},
+		{
+			name: "regexp", opts: []Option{AllowOriginRegexp(regexp.MustCompile(`^http://localhost:\d+$`))},
+			method: http.MethodGet, path: "/user/1",
+			header:   http.Header{"Origin": {"http://localhost:8080"}},
+			wantCode: http.StatusOK, wantBody: "user",
+			wantHeader: http.Header{
+				"Vary":                        {"Origin"},
+				"Access-Control-Allow-Origin": {"http://localhost:8080"},
+			},
+		},
+		{
+			name: "func", opts: []Option{AllowOriginFunc(func(origin string) bool { return strings.HasSuffix(origin, ".cn") })},
+			method: http.MethodGet, path: "/user/1",
+			header:   http.Header{"Origin": {"https://a.cn"}},
+			wantCode: http.StatusOK, wantBody: "user",
+			wantHeader: http.Header{
+				"Vary":                        {"Origin"},
+				"Access-Control-Allow-Origin": {"https://a.cn"},
+			},
+		},
+		{
+			name: "preflight", opts: []Option{AllowOrigins("https://a.com"), MaxAge(10 * time.Minute)},
+			method: http.MethodOptions, path: "/user/1",
+			header: http.Header{
+				"Origin":                         {"https://a.com"},
+				"Access-Control-Request-Method":  {"DELETE"},
+				"Access-Control-Request-Headers": {"Content-Type"},
+			},
+			wantCode: http.StatusNoContent,
+			wantHeader: http.Header{
+				"Vary":                         {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+				"Access-Control-Allow-Origin":  {"https://a.com"},
+				"Access-Control-Allow-Methods": {"GET, POST, DELETE"},
+				"Access-Control-Allow-Headers": {"Content-Type"},
+				"Access-Control-Max-Age":       {"600"},
+			},
+		},
+		{
+			name: "preflight configured", opts: []Option{AllowOrigins("*"), AllowMethods("GET"), AllowHeaders("X-Token")},
+			method: http.MethodOptions, path: "/user/1",
+			header: http.Header{
+				"Origin":                        {"https://a.com"},
+				"Access-Control-Request-Method": {"GET"},
+			},
+			wantCode: http.StatusNoContent,
+			wantHeader: http.Header{
+				"Vary":                         {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+				"Access-Control-Allow-Origin":  {"*"},
+				"Access-Control-Allow-Methods": {"GET"},
+				"Access-Control-Allow-Headers": {"X-Token"},
+			},
+		},
+		{
+			name: "preflight not allowed", opts: []Option{AllowOrigins("https://a.com")},
+			method: http.MethodOptions, path: "/user/1",
+			header: http.Header{
+				"Origin":                        {"https://b.com"},
+				"Access-Control-Request-Method": {"GET"},
+			},
+			wantCode: http.StatusNoContent,
+			wantHeader: http.Header{
+				"Vary": {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		{
+			name: "preflight not found", opts: []Option{AllowOrigins("*")},
+			method: http.MethodOptions, path: "/missing",
+			header: http.Header{
+				"Origin":                        {"https://a.com"},
+				"Access-Control-Request-Method": {"GET"},
+			},
+			wantCode: http.StatusNotFound, wantBody: "NOT FOUND",
+			wantHeader: http.Header{
+				"Vary": {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newServer(tc.opts...)
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			got := recorder.Header().Clone()
+			// 只关心 CORS 相关的头部
+			got.Del(web.DefaultRequestIDHeader)
+			got.Del("Content-Type")
+			assert.Equal(t, tc.wantHeader, got)
+		})
+	}
+}
+
+func TestNew_AllowAllWithCredentials(t *testing.T) {
+	assert.PanicsWithValue(t, `cors: AllowOrigins("*") 不能和 AllowCredentials 一起使用，请用 AllowOriginFunc 明确判断来源`, func() {
+		New(web.NewHTTPServer(), AllowOrigins("*"), AllowCredentials())
+	})
+	assert.NotPanics(t, func() {
+		New(web.NewHTTPServer(), AllowOriginFunc(func(string) bool { return true }), AllowCredentials())
+	})
+}
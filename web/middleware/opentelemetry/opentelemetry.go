@@ -0,0 +1,108 @@
+// Package opentelemetry 链路追踪，每个请求创建一个 server span，比如：
+//
+//	h.Use(opentelemetry.New(opentelemetry.ServerName("user-service")))
+//
+// 请求头里面的 W3C traceparent 和 baggage 会被解析出来作为父 span，
+// span 放在 ctx.Req.Context() 里面，业务逻辑可以用它创建子 span。
+// span 的名字是命中的路由，比如 /user/:id，没有命中的时候是 HTTP GET 这种形式
+package opentelemetry
+
+import (
+	"fmt"
+	"net/http"
+
+	"geektimeGoClass/web"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "geektimeGoClass/web/middleware/opentelemetry"
+
+// Option 链路追踪的选项
+type Option func(b *builder)
+
+// TracerProvider 默认是 otel.GetTracerProvider()
+func TracerProvider(tp trace.TracerProvider) Option {
+	return func(b *builder) {
+		b.tp = tp
+	}
+}
+
+// Propagators 默认是 W3C 的 TraceContext 和 Baggage
+func Propagators(p propagation.TextMapPropagator) Option {
+	return func(b *builder) {
+		b.propagators = p
+	}
+}
+
+// ServerName 服务的名字，记录在 http.server_name 里面
+func ServerName(name string) Option {
+	return func(b *builder) {
+		b.serverName = name
+	}
+}
+
+type builder struct {
+	tp          trace.TracerProvider
+	propagators propagation.TextMapPropagator
+	serverName  string
+}
+
+// New 创建链路追踪的 middleware，通常用 HttpServer.Use 注册。
+// 业务逻辑 panic 的时候会记录在 span 上，然后继续 panic
+func New(opts ...Option) web.Middleware {
+	b := &builder{
+		tp:          otel.GetTracerProvider(),
+		propagators: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	tracer := b.tp.Tracer(instrumentationName)
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			req := ctx.Req
+			parent := b.propagators.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			// 作为全局的 middleware 的时候，这个时候还不知道命中的路由
+			route := ctx.Route()
+			spanCtx, span := tracer.Start(parent, spanName(req, route),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(b.serverName, route, req)...),
+			)
+			ctx.Req = req.WithContext(spanCtx)
+			rec := web.Record(ctx)
+
+			defer func() {
+				// 路由在 next 里面才会查找，panic 的时候也已经有了
+				if r := ctx.Route(); r != route {
+					span.SetName(spanName(req, r))
+					span.SetAttributes(semconv.HTTPRouteKey.String(r))
+				}
+				if p := recover(); p != nil {
+					span.RecordError(fmt.Errorf("panic: %v", p), trace.WithStackTrace(true))
+					span.SetStatus(codes.Error, fmt.Sprint(p))
+					span.End()
+					panic(p)
+				}
+				status := rec.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(status)...)
+				span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(status, trace.SpanKindServer))
+				span.End()
+			}()
+			next(ctx)
+		}
+	}
+}
+
+func spanName(req *http.Request, route string) string {
+	if route != "" {
+		return route
+	}
+	return "HTTP " + req.Method
+}
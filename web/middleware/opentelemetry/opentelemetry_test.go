@@ -0,0 +1,97 @@
+package opentelemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"geektimeGoClass/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	var (
+		member      string
+		handlerSpan trace.SpanContext
+	)
+	h := web.NewHTTPServer()
+	h.Use(New(TracerProvider(tp), ServerName("user-service")))
+	h.Get("/user/:id", func(ctx *web.Context) {
+		handlerSpan = trace.SpanContextFromContext(ctx.Req.Context())
+		member = baggage.FromContext(ctx.Req.Context()).Member("tenant").Value()
+		_, _ = ctx.Resp.Write([]byte("hello"))
+	})
+	h.Post("/fail", func(ctx *web.Context) {
+		ctx.Resp.WriteHeader(http.StatusInternalServerError)
+	})
+	h.Get("/panic", func(ctx *web.Context) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/user/123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("baggage", "tenant=abc")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/fail", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.PanicsWithValue(t, "boom", func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	})
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 4)
+
+	// 父 span 来自 traceparent，业务逻辑拿到的是新建的 span
+	span := spans[0]
+	assert.Equal(t, "/user/:id", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.Parent.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	assert.True(t, span.Parent.IsRemote())
+	assert.Equal(t, span.SpanContext, handlerSpan)
+	assert.Equal(t, "abc", member)
+	assert.Equal(t, codes.Unset, span.Status.Code)
+	assertAttr(t, span.Attributes, semconv.HTTPRouteKey.String("/user/:id"))
+	assertAttr(t, span.Attributes, semconv.HTTPStatusCodeKey.Int(http.StatusOK))
+	assertAttr(t, span.Attributes, semconv.HTTPServerNameKey.String("user-service"))
+
+	span = spans[1]
+	assert.Equal(t, "/fail", span.Name)
+	assert.False(t, span.Parent.IsValid())
+	assert.Equal(t, codes.Error, span.Status.Code)
+	assertAttr(t, span.Attributes, semconv.HTTPStatusCodeKey.Int(http.StatusInternalServerError))
+
+	span = spans[2]
+	assert.Equal(t, "HTTP GET", span.Name)
+	// 4xx 对于服务端来说不算错误
+	assert.Equal(t, codes.Unset, span.Status.Code)
+	assertAttr(t, span.Attributes, semconv.HTTPStatusCodeKey.Int(http.StatusNotFound))
+
+	span = spans[3]
+	assert.Equal(t, "/panic", span.Name)
+	assert.Equal(t, codes.Error, span.Status.Code)
+	assert.Equal(t, "boom", span.Status.Description)
+	require.Len(t, span.Events, 1)
+	assert.Equal(t, "exception", span.Events[0].Name)
+}
+
+func assertAttr(t *testing.T, attrs []attribute.KeyValue, want attribute.KeyValue) {
+	for _, attr := range attrs {
+		if attr.Key == want.Key {
+			assert.Equal(t, want.Value, attr.Value, string(want.Key))
+			return
+		}
+	}
+	t.Errorf("没有属性 %s", want.Key)
+}
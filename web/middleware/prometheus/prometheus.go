@@ -0,0 +1,141 @@
+// Package prometheus 请求的监控指标，比如：
+//
+//	reg := prom.NewRegistry() // prom 是 github.com/prometheus/client_golang/prometheus
+//	mdl, err := prometheus.New(prometheus.Registerer(reg))
+//	h.Use(mdl)
+//	h.Get("/metrics", prometheus.Handler(reg))
+//
+// 请求数和耗时按照 HTTP 方法、命中的路由和状态码区分，路由是注册时的路径，比如 /user/:id，
+// 没有命中路由的请求路由是空字符串；不是标准 HTTP 方法的请求方法记为 OTHER，
+// 避免客户端随意构造的方法让指标无限增长
+package prometheus
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"geektimeGoClass/web"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Option 监控指标的选项
+type Option func(b *builder)
+
+// Namespace 指标名字的前缀
+func Namespace(namespace string) Option {
+	return func(b *builder) {
+		b.namespace = namespace
+	}
+}
+
+// Subsystem 指标名字在 Namespace 之后的前缀
+func Subsystem(subsystem string) Option {
+	return func(b *builder) {
+		b.subsystem = subsystem
+	}
+}
+
+// Buckets 耗时的分桶，单位是秒，默认是 prometheus.DefBuckets
+func Buckets(buckets []float64) Option {
+	return func(b *builder) {
+		b.buckets = buckets
+	}
+}
+
+// Registerer 注册指标的地方，默认是 prometheus.DefaultRegisterer
+func Registerer(r prometheus.Registerer) Option {
+	return func(b *builder) {
+		b.registerer = r
+	}
+}
+
+type builder struct {
+	namespace  string
+	subsystem  string
+	buckets    []float64
+	registerer prometheus.Registerer
+}
+
+// New 注册下面三个指标并返回 middleware，通常用 HttpServer.Use 注册：
+//   - http_requests_total 请求数，标签是 method、route 和 status
+//   - http_request_duration_seconds 耗时，标签同上
+//   - http_requests_in_flight 正在处理的请求数，标签只有 method，
+//     因为作为全局的 middleware 的时候，请求处理完之前还不知道命中的路由
+//
+// 指标已经注册过的时候返回错误
+func New(opts ...Option) (web.Middleware, error) {
+	b := &builder{
+		buckets:    prometheus.DefBuckets,
+		registerer: prometheus.DefaultRegisterer,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	labels := []string{"method", "route", "status"}
+	total := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: b.namespace,
+		Subsystem: b.subsystem,
+		Name:      "http_requests_total",
+		Help:      "HTTP 请求数",
+	}, labels)
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: b.namespace,
+		Subsystem: b.subsystem,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP 请求的耗时",
+		Buckets:   b.buckets,
+	}, labels)
+	inFlight := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: b.namespace,
+		Subsystem: b.subsystem,
+		Name:      "http_requests_in_flight",
+		Help:      "正在处理的 HTTP 请求数",
+	}, []string{"method"})
+	for _, c := range []prometheus.Collector{total, duration, inFlight} {
+		if err := b.registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			start := time.Now()
+			method := methodLabel(ctx.Req.Method)
+			gauge := inFlight.WithLabelValues(method)
+			gauge.Inc()
+			defer gauge.Dec()
+
+			rec := web.Record(ctx)
+			next(ctx)
+
+			status := rec.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			lvs := []string{method, ctx.Route(), strconv.Itoa(status)}
+			total.WithLabelValues(lvs...).Inc()
+			duration.WithLabelValues(lvs...).Observe(time.Since(start).Seconds())
+		}
+	}, nil
+}
+
+// methodLabel 只保留标准的 HTTP 方法
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// Handler 暴露 g 里面的指标，比如 h.Get("/metrics", Handler(prometheus.DefaultGatherer))
+func Handler(g prometheus.Gatherer) web.HandleFunc {
+	h := promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+	return func(ctx *web.Context) {
+		h.ServeHTTP(ctx.Resp, ctx.Req)
+	}
+}
@@ -0,0 +1,77 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"geektimeGoClass/web"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	mdl, err := New(Namespace("geektime"), Subsystem("web"), Registerer(reg), Buckets([]float64{1}))
+	require.NoError(t, err)
+
+	var inFlight float64
+	h := web.NewHTTPServer()
+	h.Use(mdl)
+	h.Get("/user/:id", func(ctx *web.Context) {
+		mfs, err := reg.Gather()
+		require.NoError(t, err)
+		for _, mf := range mfs {
+			if mf.GetName() == "geektime_web_http_requests_in_flight" {
+				inFlight = mf.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+		_, _ = ctx.Resp.Write([]byte("hello"))
+	})
+	h.Post("/fail", func(ctx *web.Context) {
+		ctx.Resp.WriteHeader(http.StatusInternalServerError)
+	})
+	h.Get("/metrics", Handler(reg))
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/user/1"},
+		{http.MethodGet, "/user/2"},
+		{http.MethodPost, "/fail"},
+		{http.MethodGet, "/missing"},
+		{"FOO", "/missing"},
+		{"BAR", "/missing"},
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+	assert.Equal(t, float64(1), inFlight)
+
+	err = testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP geektime_web_http_requests_total HTTP 请求数
+# TYPE geektime_web_http_requests_total counter
+geektime_web_http_requests_total{method="GET",route="",status="404"} 1
+geektime_web_http_requests_total{method="GET",route="/user/:id",status="200"} 2
+geektime_web_http_requests_total{method="OTHER",route="",status="404"} 2
+geektime_web_http_requests_total{method="POST",route="/fail",status="500"} 1
+# HELP geektime_web_http_requests_in_flight 正在处理的 HTTP 请求数
+# TYPE geektime_web_http_requests_in_flight gauge
+geektime_web_http_requests_in_flight{method="GET"} 0
+geektime_web_http_requests_in_flight{method="OTHER"} 0
+geektime_web_http_requests_in_flight{method="POST"} 0
+`), "geektime_web_http_requests_total", "geektime_web_http_requests_in_flight")
+	assert.NoError(t, err)
+	count, err := testutil.GatherAndCount(reg, "geektime_web_http_request_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `geektime_web_http_request_duration_seconds_count{method="GET",route="/user/:id",status="200"} 2`)
+
+	// 重复注册
+	_, err = New(Namespace("geektime"), Subsystem("web"), Registerer(reg))
+	assert.Error(t, err)
+}
@@ -0,0 +1,161 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// logMiddleware 把 name 写到响应里，用来检查执行顺序
+func logMiddleware(name string) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			_, _ = ctx.Resp.Write([]byte(name + " "))
+			next(ctx)
+		}
+	}
+}
+
+func TestHttpServer_Use(t *testing.T) {
+	h := NewHTTPServer()
+	h.Use(logMiddleware("a"), logMiddleware("b"))
+	h.Use(logMiddleware("c"))
+	h.Get("/user", func(ctx *Context) {
+		_, _ = ctx.Resp.Write([]byte("user"))
+	}, logMiddleware("route1"), logMiddleware("route2"))
+
+	testCases := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "route", path: "/user", wantCode: http.StatusOK, wantBody: "a b c route1 route2 user"},
+		{name: "not found", path: "/order", wantCode: http.StatusNotFound, wantBody: "a b c NOT FOUND"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+// upperWriter 模拟 gzip 之类替换 ResponseWriter 的 middleware
+type upperWriter struct {
+	http.ResponseWriter
+}
+
+func (w upperWriter) Write(b []byte) (int, error) {
+	return w.ResponseWriter.Write([]byte(strings.ToUpper(string(b))))
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	upper := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("X-Upper", "true")
+			next.ServeHTTP(upperWriter{ResponseWriter: w}, r)
+		})
+	}
+	// 在路由之前修改路径
+	strip := func(next http.Handler) http.Handler {
+		return http.StripPrefix("/api", next)
+	}
+	var restored bool
+	h := NewHTTPServer()
+	h.Use(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			resp := ctx.Resp
+			next(ctx)
+			restored = ctx.Resp == resp
+		}
+	})
+	h.Use(HTTPMiddleware(strip))
+	h.Get("/user/:id", func(ctx *Context) {
+		id, _ := ctx.PathValue("id")
+		c, ok := FromRequest(ctx.Req)
+		assert.True(t, ok)
+		assert.Same(t, ctx, c)
+		_, _ = ctx.Resp.Write([]byte("user " + id + " " + ctx.Req.Header.Get("X-Upper")))
+	}, HTTPMiddleware(upper))
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/user/abc", nil))
+	assert.Equal(t, "USER ABC TRUE", recorder.Body.String())
+	assert.True(t, restored)
+
+	// 没有经过 HTTPMiddleware 的请求
+	_, ok := FromRequest(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.False(t, ok)
+
+	// mw 丢掉了 context
+	h = NewHTTPServer()
+	h.Get("/", func(ctx *Context) {}, HTTPMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.Background()))
+		})
+	}))
+	assert.PanicsWithValue(t, "web: HTTPMiddleware 传给下一个 handler 的请求里面没有 *web.Context", func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestHTTPMiddleware_Goroutine(t *testing.T) {
+	var (
+		route string
+		user  any
+	)
+	h := NewHTTPServer()
+	h.Use(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			route = ctx.Route()
+			user, _ = ctx.Get("user")
+		}
+	})
+	// http.TimeoutHandler 在另外的 goroutine 里面调用下一个 handler，超时之后直接返回
+	h.Use(HTTPMiddleware(func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, 50*time.Millisecond, "timeout")
+	}))
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	h.Get("/slow/:id", func(ctx *Context) {
+		<-release
+		id, _ := ctx.PathValue("id")
+		ctx.Set("user", id)
+		_, _ = ctx.Resp.Write([]byte(id))
+		close(finished)
+	})
+	h.Get("/fast/:id", func(ctx *Context) {
+		id, _ := ctx.PathValue("id")
+		ctx.Set("user", id)
+		_, _ = ctx.Resp.Write([]byte(id))
+	})
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fast/1", nil))
+	assert.Equal(t, "1", recorder.Body.String())
+	assert.Equal(t, "/fast/:id", route)
+	assert.Equal(t, "1", user)
+
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow/2", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "timeout", recorder.Body.String())
+	// 超时的时候丢弃副本上的结果
+	assert.Equal(t, "", route)
+	assert.Nil(t, user)
+
+	// 原来的 Context 已经被复用，还在执行的 handler 不受影响
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fast/3", nil))
+	assert.Equal(t, "3", recorder.Body.String())
+	close(release)
+	<-finished
+}
@@ -0,0 +1,109 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type paramsKey struct{}
+
+// MountOption Mount 的选项
+type MountOption func(m *mount)
+
+// MountKeepPrefix 不去掉前缀，把完整的路径交给 handler，
+// 适合 pprof 这种自己检查完整路径的 handler
+func MountKeepPrefix() MountOption {
+	return func(m *mount) {
+		m.keepPrefix = true
+	}
+}
+
+// Mount 把 handler 挂在 prefix 下面，比如另外一个 gin 的 Engine、beego 的 App 或者 expvar.Handler()，
+// prefix 本身和它下面的所有路径，所有的标准 HTTP 方法都交给 handler 处理。
+// 默认会去掉 prefix，handler 看到的路径以 / 开头，比如挂在 /legacy 下面时 /legacy/user 变成 /user，
+// 路径末尾的 / 会保留下来。
+// prefix 里面可以有参数路径，handler 通过 RequestParams 拿到这些参数
+func (h *HttpServer) Mount(prefix string, handler http.Handler, opts ...MountOption) {
+	m := &mount{
+		handler: handler,
+		segs:    strings.Count(prefix, "/"),
+	}
+	if prefix == "/" {
+		m.segs = 0
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	sub := strings.TrimSuffix(prefix, "/") + "/*"
+	for _, method := range standardMethods {
+		h.Handle(method, prefix, m.serve)
+		h.Handle(method, sub, m.serve)
+	}
+}
+
+// RequestParams 返回 Mount 的 prefix 里面的路径参数
+func RequestParams(r *http.Request) []Param {
+	params, _ := r.Context().Value(paramsKey{}).([]Param)
+	return params
+}
+
+type mount struct {
+	handler    http.Handler
+	segs       int
+	keepPrefix bool
+}
+
+func (m *mount) serve(ctx *Context) {
+	req := ctx.Req
+	if params := ctx.PathParams(); len(params) > 0 {
+		// Context 复用的时候会覆盖参数，handler 里面可能会启动 goroutine，所以复制一份
+		params = append([]Param(nil), params...)
+		req = req.WithContext(context.WithValue(req.Context(), paramsKey{}, params))
+	}
+	if !m.keepPrefix {
+		req = m.strip(req)
+	}
+	m.handler.ServeHTTP(ctx.Resp, req)
+}
+
+// strip 和 http.StripPrefix 一样复制一份请求，去掉前面 m.segs 段
+func (m *mount) strip(req *http.Request) *http.Request {
+	r := req.WithContext(req.Context())
+	u := new(url.URL)
+	*u = *req.URL
+	u.Path = stripSegments(req.URL.Path, m.segs)
+	u.RawPath = ""
+	if req.URL.RawPath != "" {
+		// 转义之后的路径去掉同样多的段，如果两者对不上就不要 RawPath 了
+		if raw := stripSegments(req.URL.RawPath, m.segs); unescapes(raw, u.Path) {
+			u.RawPath = raw
+		}
+	}
+	r.URL = u
+	return r
+}
+
+// stripSegments 清理路径之后去掉前面 n 段，保留末尾的 /
+func stripSegments(p string, n int) string {
+	trailing := strings.HasSuffix(p, "/")
+	p = cleanPath(p)
+	for i := 0; i < n && p != "/"; i++ {
+		next := strings.IndexByte(p[1:], '/')
+		if next < 0 {
+			p = "/"
+			break
+		}
+		p = p[next+1:]
+	}
+	if trailing && p != "/" {
+		p += "/"
+	}
+	return p
+}
+
+func unescapes(raw string, p string) bool {
+	res, err := url.PathUnescape(raw)
+	return err == nil && res == p
+}
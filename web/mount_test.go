@@ -0,0 +1,73 @@
+package web
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpServer_Mount(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s %s %s %v", r.Method, r.URL.Path, r.URL.RawPath, RequestParams(r))
+	})
+	h := NewHTTPServer()
+	h.Get("/legacy/native", func(ctx *Context) {
+		_, _ = ctx.Resp.Write([]byte("native"))
+	})
+	h.Mount("/legacy", echo)
+	h.Mount("/tenant/:tenant/app", echo)
+	h.Mount("/debug/vars", expvar.Handler(), MountKeepPrefix())
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		wantBody string
+	}{
+		{name: "prefix", method: http.MethodGet, path: "/legacy", wantBody: "GET /  []"},
+		{name: "prefix slash", method: http.MethodGet, path: "/legacy/", wantBody: "GET /  []"},
+		{name: "sub path", method: http.MethodPost, path: "/legacy/user/123", wantBody: "POST /user/123  []"},
+		{name: "keep trailing slash", method: http.MethodDelete, path: "/legacy/user/", wantBody: "DELETE /user/  []"},
+		{name: "unclean", method: http.MethodGet, path: "/legacy//user/../order", wantBody: "GET /order  []"},
+		{name: "raw path", method: http.MethodGet, path: "/legacy/a%2Fb", wantBody: "GET /a/b /a%2Fb []"},
+		{name: "static first", method: http.MethodGet, path: "/legacy/native", wantBody: "native"},
+		{
+			name: "params", method: http.MethodPut, path: "/tenant/abc/app/user",
+			wantBody: "PUT /user  [{tenant abc}]",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, nil))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	assert.Contains(t, recorder.Body.String(), `"memstats"`)
+}
+
+func TestStripSegments(t *testing.T) {
+	testCases := []struct {
+		path string
+		n    int
+		want string
+	}{
+		{path: "/a/b/c", n: 0, want: "/a/b/c"},
+		{path: "/a/b/c", n: 1, want: "/b/c"},
+		{path: "/a/b/c", n: 3, want: "/"},
+		{path: "/a/b/c/", n: 1, want: "/b/c/"},
+		{path: "/a/", n: 1, want: "/"},
+		{path: "/a/b", n: 5, want: "/"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, stripSegments(tc.path, tc.n), tc.path)
+	}
+}
@@ -0,0 +1,469 @@
+package web
+
+import (
+	"regexp"
+	"strings"
+)
+
+// radixRouter 基于压缩前缀树（radix tree）的路由
+// 和 router 支持同样的路由规则，注册时的校验和返回的错误也完全一样，
+// 区别在于静态路径不再按 / 切分：
+// 连续的静态路径会被压缩到一个节点，不同的路由可以在段的内部共享前缀，
+// 查找子节点的时候只需要比较首字节，不需要对每一段做 map 查找。
+// 参数路径、正则路径和通配符路径仍然以段为单位，挂在以 / 结尾的节点上
+type radixRouter struct {
+	// 每个 HTTP 方法一棵树，方法的数量很少，遍历切片比查 map 快
+	trees []radixTree
+}
+
+type radixTree struct {
+	method string
+	root   *radixNode
+}
+
+// NewRadixRouter 基于压缩前缀树的路由实现
+// 它的节点是原地修改的，所以只能在服务启动之前注册路由，也不支持删除路由
+func NewRadixRouter() Router {
+	return newRadixRouter()
+}
+
+var _ Router = &radixRouter{}
+
+func newRadixRouter() *radixRouter {
+	return &radixRouter{}
+}
+
+// root 返回 method 的根节点，没有的时候返回 nil
+func (r *radixRouter) root(method string) *radixNode {
+	for _, t := range r.trees {
+		if t.method == method {
+			return t.root
+		}
+	}
+	return nil
+}
+
+type radixNode struct {
+	// 压缩后的静态前缀，可能跨越多个段，也可能只是某一段的一部分
+	// 参数、正则和通配符节点则是注册时的那一段，比如 :id
+	prefix string
+
+	// indices[i] 是 children[i].prefix 的首字节
+	indices  string
+	children []*radixNode
+	// 子节点比较多的时候按首字节直接查表，table[c-lo] 是首字节为 c 的子节点
+	lo    byte
+	table []*radixNode
+
+	// 动态子节点只会出现在以 / 结尾的节点（或者根节点）上
+	starChild  *radixNode
+	paramChild *radixNode
+	regChild   *radixNode
+
+	// 参数路径和正则路径的参数名
+	paramName string
+	// 正则路径注册时编译好的正则表达式
+	regExpr *regexp.Regexp
+
+	handler HandleFunc
+	// 注册时的完整路径，只有 handler 不为 nil 的节点才有
+	route string
+}
+
+// addRoute 和 AddRoute 一样，但是出错的时候 panic
+func (r *radixRouter) addRoute(method string, path string, handleFunc HandleFunc) {
+	if err := r.AddRoute(method, path, handleFunc); err != nil {
+		panic(err.Error())
+	}
+}
+
+// AddRoute 的限制和 router.AddRoute 完全一致。
+// 节点是原地修改的，所以先完整地校验一遍，没有问题之后再插入，出错的时候路由树保持不变
+func (r *radixRouter) AddRoute(method string, path string, handleFunc HandleFunc) error {
+	if path == "" {
+		return ErrEmptyPath
+	}
+	if path[0] != '/' {
+		return ErrPathNoLeadingSlash
+	}
+
+	root := r.root(method)
+	if path == "/" {
+		if root != nil && root.handler != nil {
+			return &ErrConflict{Existing: path, New: path}
+		}
+		root = r.rootOrCreate(method)
+		root.handler = handleFunc
+		root.route = path
+		return nil
+	}
+
+	if path[len(path)-1] == '/' {
+		return ErrPathTrailingSlash
+	}
+
+	segs := strings.Split(path[1:], "/")
+	for _, seg := range segs {
+		if seg == "" {
+			return ErrPathDoubleSlash
+		}
+	}
+	if err := root.check(path, segs); err != nil {
+		return err
+	}
+
+	cur := r.rootOrCreate(method)
+	// 还没有插入的静态部分
+	var static strings.Builder
+	for i, seg := range segs {
+		if i > 0 {
+			static.WriteByte('/')
+		}
+		if seg[0] != ':' && seg != "*" {
+			static.WriteString(seg)
+			continue
+		}
+		cur = cur.insertStatic(static.String())
+		static.Reset()
+		// 已经校验过了，不会出错
+		cur, _ = cur.dynamicChildOrCreate(seg)
+	}
+	cur = cur.insertStatic(static.String())
+	cur.handler = handleFunc
+	cur.route = path
+	return nil
+}
+
+func (r *radixRouter) rootOrCreate(method string) *radixNode {
+	root := r.root(method)
+	if root == nil {
+		root = &radixNode{
+			prefix: "/",
+		}
+		r.trees = append(r.trees, radixTree{method: method, root: root})
+	}
+	return root
+}
+
+// check 按照插入的顺序走一遍，检查 segs 会不会和已有的路由冲突，以及正则表达式是否合法，不修改任何节点。
+// n 为 nil 表示已经离开了已有的路由树，后面的段不会再有冲突
+func (n *radixNode) check(path string, segs []string) error {
+	var static strings.Builder
+	for i, seg := range segs {
+		if i > 0 {
+			static.WriteByte('/')
+		}
+		if seg[0] != ':' && seg != "*" {
+			static.WriteString(seg)
+			continue
+		}
+		n = n.findStatic(static.String())
+		static.Reset()
+		var err error
+		n, _, err = n.dynamicChild(seg)
+		if err != nil {
+			return err
+		}
+	}
+	if n = n.findStatic(static.String()); n != nil && n.handler != nil {
+		return &ErrConflict{Existing: path, New: path}
+	}
+	return nil
+}
+
+// findStatic 查找恰好在静态路径 s 结尾处结束的节点，不存在的时候返回 nil
+func (n *radixNode) findStatic(s string) *radixNode {
+	for n != nil && s != "" {
+		c := n.child(s[0])
+		if c == nil || !strings.HasPrefix(s, c.prefix) {
+			return nil
+		}
+		n, s = c, s[len(c.prefix):]
+	}
+	return n
+}
+
+func (r *radixRouter) Routes() []RouteInfo {
+	var res []RouteInfo
+	for _, t := range r.trees {
+		method := t.method
+		t.root.walk(func(n *radixNode) {
+			if n.handler != nil {
+				res = append(res, RouteInfo{Method: method, Pattern: n.route, Handler: n.handler})
+			}
+		})
+	}
+	sortRoutes(res)
+	return res
+}
+
+func (n *radixNode) walk(fn func(n *radixNode)) {
+	fn(n)
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+	for _, c := range []*radixNode{n.regChild, n.paramChild, n.starChild} {
+		if c != nil {
+			c.walk(fn)
+		}
+	}
+}
+
+// insertStatic 插入静态路径 s，返回恰好在 s 结尾处结束的节点，必要的时候拆分已有节点
+func (n *radixNode) insertStatic(s string) *radixNode {
+	for s != "" {
+		i := n.indexOf(s[0])
+		if i < 0 {
+			child := &radixNode{prefix: s}
+			n.indices += s[:1]
+			n.children = append(n.children, child)
+			n.buildTable()
+			return child
+		}
+		child := n.children[i]
+		l := commonPrefix(s, child.prefix)
+		if l < len(child.prefix) {
+			// 拆分：原来的节点变成公共前缀节点的子节点
+			split := &radixNode{
+				prefix:   child.prefix[:l],
+				indices:  child.prefix[l : l+1],
+				children: []*radixNode{child},
+			}
+			child.prefix = child.prefix[l:]
+			n.children[i] = split
+			n.buildTable()
+			child = split
+		}
+		n = child
+		s = s[l:]
+	}
+	return n
+}
+
+// dynamicChildOrCreate 的规则和 node.childOrCreate 一致
+func (n *radixNode) dynamicChildOrCreate(seg string) (*radixNode, error) {
+	child, expr, err := n.dynamicChild(seg)
+	if err != nil || child != nil {
+		return child, err
+	}
+	child = &radixNode{prefix: seg}
+	switch {
+	case expr != nil:
+		child.paramName = regPathPattern.FindStringSubmatch(seg)[1]
+		child.regExpr = expr
+		n.regChild = child
+	case seg[0] == ':':
+		child.paramName = seg[1:]
+		n.paramChild = child
+	default:
+		n.starChild = child
+	}
+	return child, nil
+}
+
+// dynamicChild 检查能不能在 n 下面注册动态的一段 seg，返回已有的同名子节点，
+// 正则路径还会返回编译好的正则表达式。n 为 nil 的时候只检查正则表达式
+func (n *radixNode) dynamicChild(seg string) (*radixNode, *regexp.Regexp, error) {
+	var star, param, reg *radixNode
+	if n != nil {
+		star, param, reg = n.starChild, n.paramChild, n.regChild
+	}
+
+	regs := regPathPattern.FindStringSubmatch(seg)
+	if regs != nil {
+		if star != nil {
+			return nil, nil, &ErrMixedParamKinds{Existing: star.prefix, New: seg}
+		}
+		if param != nil {
+			return nil, nil, &ErrMixedParamKinds{Existing: param.prefix, New: seg}
+		}
+		if reg != nil && reg.prefix != seg {
+			return nil, nil, &ErrConflict{Existing: reg.prefix, New: seg}
+		}
+		expr, err := regexp.Compile(regs[2])
+		if err != nil {
+			return nil, nil, &ErrBadRegex{Expr: regs[2], Err: err}
+		}
+		return reg, expr, nil
+	}
+
+	if seg[0] == ':' {
+		if star != nil {
+			return nil, nil, &ErrMixedParamKinds{Existing: star.prefix, New: seg}
+		}
+		if reg != nil {
+			return nil, nil, &ErrMixedParamKinds{Existing: reg.prefix, New: seg}
+		}
+		if param != nil && param.prefix != seg {
+			return nil, nil, &ErrConflict{Existing: param.prefix, New: seg}
+		}
+		return param, nil, nil
+	}
+
+	if param != nil {
+		return nil, nil, &ErrMixedParamKinds{Existing: param.prefix, New: seg}
+	}
+	if reg != nil {
+		return nil, nil, &ErrMixedParamKinds{Existing: reg.prefix, New: seg}
+	}
+	return star, nil, nil
+}
+
+// buildTable 子节点超过 4 个的时候重新生成 table
+func (n *radixNode) buildTable() {
+	if len(n.indices) <= 4 {
+		n.table = nil
+		return
+	}
+	lo, hi := n.indices[0], n.indices[0]
+	for i := 1; i < len(n.indices); i++ {
+		if c := n.indices[i]; c < lo {
+			lo = c
+		} else if c > hi {
+			hi = c
+		}
+	}
+	n.lo = lo
+	n.table = make([]*radixNode, int(hi-lo)+1)
+	for i, c := range n.children {
+		n.table[n.indices[i]-lo] = c
+	}
+}
+
+// child 返回首字节是 c 的静态子节点
+func (n *radixNode) child(c byte) *radixNode {
+	if n.table != nil {
+		if i := int(c) - int(n.lo); i >= 0 && i < len(n.table) {
+			return n.table[i]
+		}
+		return nil
+	}
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] == c {
+			return n.children[i]
+		}
+	}
+	return nil
+}
+
+func (n *radixNode) indexOf(c byte) int {
+	return strings.IndexByte(n.indices, c)
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// FindRoute 的查找顺序和 router.match 一致：
+// 每一段优先静态匹配，其次是正则或者参数路径，最后是通配符；
+// 后面的段匹配失败的时候，回退到最近一次经过的通配符节点。
+// 和 httprouter 一样，静态部分是整个前缀一起比较的，不需要按 / 切分路径，
+// 只有静态匹配失败的时候才回到这一段的开头，尝试动态子节点
+func (r *radixRouter) FindRoute(method string, path string, m *Match) bool {
+	root := r.root(method)
+	if root == nil {
+		return false
+	}
+	path = strings.Trim(path, "/")
+	if path == "" {
+		m.Handler = root.handler
+		m.Pattern = root.route
+		return true
+	}
+
+	params := m.Params
+	// n 的前缀已经全部匹配，对应 path[:i]
+	n, i := root, 0
+	// dynamic 表示 n 是刚刚匹配的参数、正则或者通配符节点，它总是在一段的结尾
+	dynamic := false
+	// 最近一次在段的开头停在节点的结尾，只有这样的节点才可能有动态子节点，
+	// segStart 为 -1 表示这一段已经用动态子节点匹配过了
+	segNode, segStart := root, 0
+	star := root.starChild
+	for {
+		// 静态匹配在 path[:fail] 之后失败，或者在某一段的中间结束
+		var fail int
+		if i == len(path) {
+			if dynamic || n.handler != nil || n.indexOf('/') >= 0 {
+				break
+			}
+			fail = i
+		} else if c := n.child(path[i]); c == nil {
+			if path[i] == '/' && (dynamic || n.handler != nil) {
+				// 这一段已经完整匹配了，是后面的段没有能够匹配的
+				return matchStar(star, params, m)
+			}
+			fail = i
+		} else if l := len(c.prefix); len(path)-i >= l && (l == 1 || path[i+1:i+l] == c.prefix[1:]) {
+			// 首字节在 child 里面已经比较过了
+			n, i, dynamic = c, i+l, false
+			if path[i-1] == '/' {
+				segNode, segStart = n, i
+				if n.starChild != nil {
+					star = n.starChild
+				}
+			}
+			continue
+		} else {
+			l = commonPrefix(path[i:], c.prefix)
+			if i+l == len(path) && c.prefix[l] == '/' {
+				// 完整地匹配了最后一段，但是停在节点的中间，说明只是某个路由的前缀
+				n = nil
+				break
+			}
+			fail = i + l
+		}
+
+		// 失败的那一段从 segNode 的结尾开始，才有动态子节点可以尝试，否则回退到通配符
+		start := strings.LastIndexByte(path[:fail], '/') + 1
+		if start != segStart {
+			return matchStar(star, params, m)
+		}
+		// 段一般都很短，直接遍历比 IndexByte 快
+		end := fail
+		for end < len(path) && path[end] != '/' {
+			end++
+		}
+		seg := path[start:end]
+		var next *radixNode
+		switch {
+		case segNode.regChild != nil:
+			if segNode.regChild.regExpr.MatchString(seg) {
+				next = segNode.regChild
+			}
+		case segNode.paramChild != nil:
+			next = segNode.paramChild
+		default:
+			next = segNode.starChild
+		}
+		if next == nil {
+			return matchStar(star, params, m)
+		}
+		if next != segNode.starChild {
+			params = append(params, Param{Key: next.paramName, Value: seg})
+		}
+		n, i, dynamic, segStart = next, end, true, -1
+	}
+	m.Handler, m.Pattern = nil, ""
+	if n != nil {
+		m.Handler = n.handler
+		m.Pattern = n.route
+	}
+	m.Params = params
+	return true
+}
+
+// matchStar 后面的段匹配失败，回退到最近一次经过的通配符节点
+func matchStar(star *radixNode, params []Param, m *Match) bool {
+	if star == nil {
+		return false
+	}
+	m.Handler = star.handler
+	m.Pattern = star.route
+	m.Params = params
+	return true
+}
@@ -0,0 +1,146 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRadixRouter_AddRoute(t *testing.T) {
+	var mockHandler HandleFunc = func(ctx *Context) {}
+
+	r := newRadixRouter()
+	r.addRoute(http.MethodGet, "/user/home", mockHandler)
+	r.addRoute(http.MethodGet, "/user/homepage", mockHandler)
+	r.addRoute(http.MethodGet, "/user", mockHandler)
+	r.addRoute(http.MethodGet, "/user/:id/detail", mockHandler)
+	r.addRoute(http.MethodGet, "/order/*", mockHandler)
+
+	// 静态路径在段的内部也会共享前缀
+	wantTree := &radixNode{
+		prefix:  "/",
+		indices: "uo",
+		children: []*radixNode{
+			{
+				prefix:  "user",
+				handler: mockHandler,
+				indices: "/",
+				children: []*radixNode{
+					{
+						prefix: "/",
+						paramChild: &radixNode{
+							prefix:    ":id",
+							paramName: "id",
+							indices:   "/",
+							children: []*radixNode{
+								{prefix: "/detail", handler: mockHandler},
+							},
+						},
+						indices: "h",
+						children: []*radixNode{
+							{
+								prefix:  "home",
+								handler: mockHandler,
+								indices: "p",
+								children: []*radixNode{
+									{prefix: "page", handler: mockHandler},
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				prefix: "order/",
+				starChild: &radixNode{
+					prefix:  "*",
+					handler: mockHandler,
+				},
+			},
+		},
+	}
+	msg, ok := r.root(http.MethodGet).equal(wantTree)
+	assert.True(t, ok, msg)
+}
+
+func (n *radixNode) equal(y *radixNode) (string, bool) {
+	if n == nil || y == nil {
+		return "空节点", false
+	}
+	if n.prefix != y.prefix {
+		return fmt.Sprintf("节点前缀不匹配 %s %s", n.prefix, y.prefix), false
+	}
+	if n.paramName != y.paramName {
+		return fmt.Sprintf("%s 参数名不匹配", n.prefix), false
+	}
+	if n.indices != y.indices || len(n.children) != len(y.children) {
+		return fmt.Sprintf("%s 子节点不相等", n.prefix), false
+	}
+	if reflect.ValueOf(n.handler) != reflect.ValueOf(y.handler) {
+		return fmt.Sprintf("%s handler 不相等", n.prefix), false
+	}
+	for _, c := range [][2]*radixNode{
+		{n.starChild, y.starChild},
+		{n.paramChild, y.paramChild},
+		{n.regChild, y.regChild},
+	} {
+		if c[0] != nil || c[1] != nil {
+			if msg, ok := c[0].equal(c[1]); !ok {
+				return msg, false
+			}
+		}
+	}
+	for i, c := range n.children {
+		if msg, ok := c.equal(y.children[i]); !ok {
+			return msg, false
+		}
+	}
+	return "", true
+}
+
+// BenchmarkRouter_Match 对比 router 和 radixRouter 在大量路由下的查找性能
+func BenchmarkRouter_Match(b *testing.B) {
+	var handler HandleFunc = func(ctx *Context) {}
+	tree := newRouter()
+	radix := newRadixRouter()
+	for i := 0; i < 1000; i++ {
+		for _, path := range []string{
+			fmt.Sprintf("/api/v1/resource%d", i),
+			fmt.Sprintf("/api/v1/resource%d/:id", i),
+			fmt.Sprintf("/api/v1/resource%d/:id/items/*", i),
+		} {
+			tree.addRoute(http.MethodGet, path, handler)
+			radix.addRoute(http.MethodGet, path, handler)
+		}
+	}
+
+	benchmarks := []struct {
+		name string
+		path string
+	}{
+		{name: "static", path: "/api/v1/resource999"},
+		{name: "param", path: "/api/v1/resource500/123"},
+		{name: "wildcard", path: "/api/v1/resource42/123/items/abc"},
+	}
+	for _, bm := range benchmarks {
+		b.Run("tree/"+bm.name, func(b *testing.B) {
+			m := &Match{}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				m.Params = m.Params[:0]
+				tree.FindRoute(http.MethodGet, bm.path, m)
+			}
+		})
+		b.Run("radix/"+bm.name, func(b *testing.B) {
+			m := &Match{}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				m.Params = m.Params[:0]
+				radix.FindRoute(http.MethodGet, bm.path, m)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/textproto"
+	"sync/atomic"
+)
+
+// DefaultRequestIDHeader 默认的请求 ID 的请求头和响应头
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// WithRequestIDHeader 设置请求 ID 使用的请求头和响应头，默认是 X-Request-ID，
+// 设置成空字符串表示不使用请求 ID
+func WithRequestIDHeader(header string) HTTPServerOption {
+	return func(server *HttpServer) {
+		server.requestIDHeader = textproto.CanonicalMIMEHeaderKey(header)
+	}
+}
+
+// WithRequestIDGenerator 设置生成请求 ID 的方法，默认生成 UUID 格式的 ID，见 newRequestIDGenerator
+func WithRequestIDGenerator(gen func() string) HTTPServerOption {
+	return func(server *HttpServer) {
+		server.requestIDGen = gen
+	}
+}
+
+// requestID 请求头里面有合法的请求 ID 就直接使用，否则生成一个新的，并且写到响应头里面
+func (h *HttpServer) requestID(ctx *Context) {
+	if h.requestIDHeader == "" {
+		return
+	}
+	// requestIDHeader 已经是规范的形式，直接访问 map 可以省掉一次转换
+	var id string
+	if vals := ctx.Req.Header[h.requestIDHeader]; len(vals) > 0 {
+		id = vals[0]
+	}
+	if !validRequestID(id) {
+		id = h.requestIDGen()
+	}
+	ctx.requestID = id
+	ctx.Resp.Header()[h.requestIDHeader] = []string{id}
+}
+
+// validRequestID 请求 ID 来自客户端，不能太长，也不能有可能破坏日志格式的字符
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':' || c == '/' || c == '+' || c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestIDGenerator 生成第 4 版 UUID 格式的请求 ID。
+// 前 8 个字节在创建的时候随机生成，后 8 个字节是递增的序号，
+// 这样每个请求不用再读一次随机数，同一个服务生成的请求 ID 也不会重复
+func newRequestIDGenerator() func() string {
+	var prefix [8]byte
+	_, _ = rand.Read(prefix[:])
+	prefix[6] = prefix[6]&0x0f | 0x40
+	var head [19]byte
+	hex.Encode(head[0:8], prefix[0:4])
+	head[8] = '-'
+	hex.Encode(head[9:13], prefix[4:6])
+	head[13] = '-'
+	hex.Encode(head[14:18], prefix[6:8])
+	head[18] = '-'
+	var seq uint64
+	return func() string {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], atomic.AddUint64(&seq, 1))
+		b[0] = b[0]&0x3f | 0x80
+		var buf [36]byte
+		copy(buf[:], head[:])
+		hex.Encode(buf[19:23], b[0:2])
+		buf[23] = '-'
+		hex.Encode(buf[24:], b[2:])
+		return string(buf[:])
+	}
+}
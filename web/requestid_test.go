@@ -0,0 +1,81 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpServer_RequestID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	testCases := []struct {
+		name      string
+		opts      []HTTPServerOption
+		header    string
+		reqID     string
+		wantID    string
+		wantUUID  bool
+		wantEmpty bool
+	}{
+		{name: "generate", header: "X-Request-ID", wantUUID: true},
+		{name: "from header", header: "X-Request-ID", reqID: "abc-123", wantID: "abc-123"},
+		{name: "invalid", header: "X-Request-ID", reqID: "abc\n123", wantUUID: true},
+		{name: "too long", header: "X-Request-ID", reqID: strings.Repeat("a", 129), wantUUID: true},
+		{
+			name: "custom", header: "X-Trace", reqID: "", wantID: "gen",
+			opts: []HTTPServerOption{WithRequestIDHeader("X-Trace"), WithRequestIDGenerator(func() string { return "gen" })},
+		},
+		{name: "disabled", header: "X-Request-ID", reqID: "abc", opts: []HTTPServerOption{WithRequestIDHeader("")}, wantEmpty: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHTTPServer(tc.opts...)
+			var got string
+			h.Get("/", func(ctx *Context) {
+				got = ctx.RequestID()
+			}, Timeout(time.Second))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.reqID != "" {
+				req.Header.Set(tc.header, tc.reqID)
+			}
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, req)
+
+			assert.Equal(t, got, recorder.Header().Get(tc.header))
+			switch {
+			case tc.wantEmpty:
+				assert.Empty(t, got)
+			case tc.wantUUID:
+				assert.Regexp(t, uuid, got)
+			default:
+				assert.Equal(t, tc.wantID, got)
+			}
+		})
+	}
+
+	// 没有命中路由的响应也有请求 ID
+	h := NewHTTPServer()
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Regexp(t, uuid, recorder.Header().Get(DefaultRequestIDHeader))
+}
+
+func TestNewRequestIDGenerator(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	gen := newRequestIDGenerator()
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		id := gen()
+		assert.Regexp(t, uuid, id)
+		assert.False(t, seen[id], id)
+		seen[id] = true
+	}
+	// 不同的服务前缀不一样
+	assert.NotEqual(t, gen()[:18], newRequestIDGenerator()()[:18])
+}
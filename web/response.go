@@ -0,0 +1,72 @@
+package web
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// ResponseRecorder 记录响应的状态码和写出的字节数，给访问日志、监控之类的 middleware 使用
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// Record 用 ResponseRecorder 包装 ctx.Resp，已经包装过的话直接返回原来的，
+// 这样多个 middleware 可以共用一个 ResponseRecorder
+func Record(ctx *Context) *ResponseRecorder {
+	if r, ok := ctx.Resp.(*ResponseRecorder); ok {
+		return r
+	}
+	r := &ResponseRecorder{ResponseWriter: ctx.Resp}
+	ctx.Resp = r
+	return r
+}
+
+func (r *ResponseRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *ResponseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Status 返回写出的状态码，还没有写响应的时候返回 0
+func (r *ResponseRecorder) Status() int {
+	return r.status
+}
+
+// Bytes 返回写出的响应体的字节数
+func (r *ResponseRecorder) Bytes() int {
+	return r.bytes
+}
+
+// Unwrap 返回原来的 ResponseWriter，http.ResponseController 会用到
+func (r *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *ResponseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+func (r *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := r.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
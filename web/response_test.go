@@ -0,0 +1,52 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	var (
+		rec   *ResponseRecorder
+		route string
+	)
+	h := NewHTTPServer()
+	h.Use(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			rec = Record(ctx)
+			// 重复调用返回同一个
+			assert.Same(t, rec, Record(ctx))
+			next(ctx)
+			route = ctx.Route()
+		}
+	}, Timeout(time.Second))
+	h.Get("/user/:id", func(ctx *Context) {
+		ctx.Resp.WriteHeader(http.StatusAccepted)
+		_, _ = ctx.Resp.Write([]byte("hello"))
+	})
+	h.Get("/empty", func(ctx *Context) {})
+
+	testCases := []struct {
+		path       string
+		wantStatus int
+		wantBytes  int
+		wantRoute  string
+	}{
+		{path: "/user/123", wantStatus: http.StatusAccepted, wantBytes: 5, wantRoute: "/user/:id"},
+		// Timeout 总是会写状态码
+		{path: "/empty", wantStatus: http.StatusOK, wantRoute: "/empty"},
+		{path: "/missing", wantStatus: http.StatusNotFound, wantBytes: 9},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantStatus, rec.Status())
+			assert.Equal(t, tc.wantBytes, rec.Bytes())
+			assert.Equal(t, tc.wantRoute, route)
+		})
+	}
+}
@@ -1,33 +1,119 @@
 package web
 
 import (
-	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// Router 路由树的抽象，HttpServer 通过 WithRouter 可以替换成不同的实现，
+// 比如默认的 NewTreeRouter，或者 NewRadixRouter。
+// 自己实现的 Router 可以用 routertest.Run 检查是否符合这里约定的行为
+type Router interface {
+	// AddRoute 注册路由，路径不合法或者和已有路由冲突时返回错误，
+	// 错误是 ErrEmptyPath 等路径格式的错误，或者 *ErrConflict，*ErrMixedParamKinds，*ErrBadRegex。
+	// 出错的时候不能留下注册了一半的路由
+	AddRoute(method string, path string, handleFunc HandleFunc) error
+
+	// FindRoute 查找路由，结果写入调用方提供的 m，这样调用方可以复用内存。
+	// 路径参数追加到 m.Params 后面。
+	// 命中了某个路由的前缀、但是没有 handler 的时候，可以返回 true 并且 m.Handler 为 nil
+	FindRoute(method string, path string, m *Match) bool
+
+	// Routes 返回所有注册了 handler 的路由
+	Routes() []RouteInfo
+}
+
+// Match 路由查找的结果
+type Match struct {
+	Handler HandleFunc
+	// Pattern 注册时的路径，比如 /user/:id
+	Pattern string
+	Params  []Param
+}
+
+// RouteInfo 一条注册的路由
+type RouteInfo struct {
+	// Host 通过 HttpServer.Host 注册的路由才有
+	Host    string
+	Method  string
+	Pattern string
+	Handler HandleFunc
+
+	// handlerName 注册时记录的 handler 的名字，见 HandlerName
+	handlerName string
+}
+
+// NewTreeRouter 默认的路由实现，按 / 切分的路径树
+func NewTreeRouter() Router {
+	return newRouter()
+}
+
+var _ CaseInsensitiveRouter = &router{}
+
+// CaseInsensitiveRouter Router 可以选择实现的接口，
+// 支持忽略静态路径的大小写查找路由，参数路径和正则路径不受影响。
+// NewTreeRouter 实现了这个接口，NewRadixRouter 没有
+type CaseInsensitiveRouter interface {
+	Router
+	FindRouteIgnoreCase(method string, path string, m *Match) bool
+}
+
+// RouteRemover Router 可以选择实现的接口，支持删除路由
+type RouteRemover interface {
+	// RemoveRoute 删除注册的路由，path 必须和注册时一致。路由不存在的时候返回 false
+	RemoveRoute(method string, path string) bool
+}
+
+var _ RouteRemover = &router{}
+
 // 用来支持对路径树的操作
 // 代表路径树（森林）
+//
+// 路径树是写时复制的：注册和删除路由的时候只复制从根节点到目标节点路径上的节点，
+// 修改完成之后原子地替换整个森林。
+// 所以查找路由不需要加锁，服务启动之后也可以安全地注册和删除路由，
+// 正在处理的请求看到的始终是某一个完整的版本
 type router struct {
+	// 串行化写操作
+	mu sync.Mutex
 	// key: HTTP method =》根节点
 	// value: 子节点 =》 path
-	trees map[string]*node
+	// 存放 map[string]*node，一旦存进去就不会再修改
+	trees atomic.Value
 }
 
 func newRouter() *router {
-	return &router{
-		trees: map[string]*node{},
+	r := &router{}
+	r.trees.Store(map[string]*node{})
+	return r
+}
+
+func (r *router) loadTrees() map[string]*node {
+	return r.trees.Load().(map[string]*node)
+}
+
+// addRoute 和 AddRoute 一样，但是出错的时候 panic
+func (r *router) addRoute(method string, path string, handleFunc HandleFunc) {
+	if err := r.AddRoute(method, path, handleFunc); err != nil {
+		panic(err.Error())
 	}
 }
 
-// addRoute 添加限制：
+// AddRoute 添加限制：
 // path 必须以 / 开头，不能以 / 结尾 且不能出现连续的 //
-func (r *router) addRoute(method string, path string, handleFunc HandleFunc) {
+func (r *router) AddRoute(method string, path string, handleFunc HandleFunc) error {
 	if path == "" {
-		panic("web：路径不能为空字符串")
+		return ErrEmptyPath
 	}
 
-	root, ok := r.trees[method]
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	trees := r.copyTrees()
+
+	root, ok := trees[method]
 	if !ok {
 		// 还没有根节点
 		root = &node{
@@ -35,24 +121,28 @@ func (r *router) addRoute(method string, path string, handleFunc HandleFunc) {
 			children: nil,
 			handler:  nil,
 		}
-		r.trees[method] = root
+	} else {
+		root = root.clone()
 	}
+	trees[method] = root
 
 	if path[0] != '/' {
-		panic("web：路径必须以 / 开头")
+		return ErrPathNoLeadingSlash
 	}
 
 	// 根节点特殊处理
 	if path == "/" {
 		if root.handler != nil {
-			panic("web: 路径冲突，重复注册[/]")
+			return &ErrConflict{Existing: path, New: path}
 		}
 		root.handler = handleFunc
-		return
+		root.route = path
+		r.trees.Store(trees)
+		return nil
 	}
 
 	if path[len(path)-1] == '/' {
-		panic("web：路径不能以 / 结尾")
+		return ErrPathTrailingSlash
 	}
 
 	// 切割 path
@@ -60,45 +150,181 @@ func (r *router) addRoute(method string, path string, handleFunc HandleFunc) {
 	segs = segs[1:]
 	for _, seg := range segs {
 		if seg == "" {
-			panic("web：路径不能出现连续的 /")
+			return ErrPathDoubleSlash
 		}
 		// 递归找children
 		// 不存在就创建
-		root = root.childOrCreate(seg)
+		var err error
+		root, err = root.childOrCreate(seg)
+		if err != nil {
+			return err
+		}
 	}
 	if root.handler != nil {
-		panic(fmt.Sprintf("web: 路径冲突，重复注册[%s]", path))
+		return &ErrConflict{Existing: path, New: path}
 	}
 	root.handler = handleFunc
+	root.route = path
+	// 中途出错的话，修改的都是副本，原来的路径树不受影响
+	r.trees.Store(trees)
+	return nil
+}
+
+// copyTrees 复制一份森林，根节点还是共享的，修改之前需要 clone
+func (r *router) copyTrees() map[string]*node {
+	old := r.loadTrees()
+	trees := make(map[string]*node, len(old)+1)
+	for method, root := range old {
+		trees[method] = root
+	}
+	return trees
+}
+
+func (r *router) RemoveRoute(method string, path string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	trees := r.copyTrees()
+	root, ok := trees[method]
+	if !ok || path == "" || path[0] != '/' {
+		return false
+	}
+	root = root.clone()
+	trees[method] = root
+	if path == "/" {
+		if root.handler == nil {
+			return false
+		}
+		root.handler = nil
+		root.route = ""
+		r.trees.Store(trees)
+		return true
+	}
+
+	// 记录经过的节点，删除之后从下往上清理空节点
+	nodes := []*node{root}
+	cur := root
+	for _, seg := range strings.Split(path[1:], "/") {
+		child := cur.childByPath(seg)
+		if child == nil {
+			return false
+		}
+		child = child.clone()
+		cur.setChild(child)
+		nodes = append(nodes, child)
+		cur = child
+	}
+	if cur.handler == nil || cur.route != path {
+		return false
+	}
+	cur.handler = nil
+	cur.route = ""
+	for i := len(nodes) - 1; i > 0; i-- {
+		if !nodes[i].empty() {
+			break
+		}
+		nodes[i-1].removeChild(nodes[i])
+	}
+	r.trees.Store(trees)
+	return true
+}
+
+func (r *router) FindRoute(method string, path string, m *Match) bool {
+	return r.find(method, path, m, false)
+}
+
+func (r *router) FindRouteIgnoreCase(method string, path string, m *Match) bool {
+	return r.find(method, path, m, true)
+}
+
+func (r *router) find(method string, path string, m *Match, ignoreCase bool) bool {
+	info := matchInfo{pathParams: m.Params}
+	if !r.match(method, path, &info, ignoreCase) {
+		return false
+	}
+	m.Handler = info.n.handler
+	m.Pattern = info.n.route
+	m.Params = info.pathParams
+	return true
+}
+
+func (r *router) Routes() []RouteInfo {
+	var res []RouteInfo
+	for method, root := range r.loadTrees() {
+		root.walk(func(n *node) {
+			if n.handler != nil {
+				res = append(res, RouteInfo{Method: method, Pattern: n.route, Handler: n.handler})
+			}
+		})
+	}
+	sortRoutes(res)
+	return res
+}
+
+// walk 深度优先遍历
+func (n *node) walk(fn func(n *node)) {
+	fn(n)
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+	for _, c := range []*node{n.regChild, n.paramChild, n.starChild} {
+		if c != nil {
+			c.walk(fn)
+		}
+	}
+}
+
+// sortRoutes 按照 HTTP 方法和路径排序，保证 Routes 的结果是稳定的
+func sortRoutes(routes []RouteInfo) {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Method != routes[j].Method {
+			return routes[i].Method < routes[j].Method
+		}
+		return routes[i].Pattern < routes[j].Pattern
+	})
 }
 
 func (r *router) findRoute(method string, path string) (*matchInfo, bool) {
+	info := &matchInfo{}
+	ok := r.match(method, path, info, false)
+	return info, ok
+}
+
+// match 与 findRoute 相同，只是结果写入调用方提供的 info，
+// 路径参数追加到 info.pathParams 后面，这样调用方可以复用内存。
+// ignoreCase 为 true 的时候静态路径忽略大小写
+func (r *router) match(method string, path string, info *matchInfo, ignoreCase bool) bool {
 	// 树的深度遍历查找
-	root, ok := r.trees[method]
+	root, ok := r.loadTrees()[method]
 	if !ok {
-		return nil, false
+		return false
 	}
 	if path == "/" {
-		return &matchInfo{
-			n: root,
-		}, true
+		info.n = root
+		return true
 	}
 
 	path = strings.Trim(path, "/")
-	segs := strings.Split(path, "/")
-	var (
-		pathParams   map[string]string
-		starNodeTemp *node
-	)
-	for _, seg := range segs {
-		child, starNode, isRegChild, isParamChild, found := root.childOf(seg)
+	params := info.pathParams
+	var starNodeTemp *node
+	// 等价于遍历 strings.Split(path, "/")，但是不需要分配切片
+	for start := 0; start <= len(path); {
+		end := strings.IndexByte(path[start:], '/')
+		if end < 0 {
+			end = len(path)
+		} else {
+			end += start
+		}
+		seg := path[start:end]
+		start = end + 1
+
+		child, starNode, isRegChild, isParamChild, found := root.childOf(seg, ignoreCase)
 
 		if !found && starNodeTemp != nil {
 			root = starNodeTemp
 			break
 		}
 		if !found {
-			return nil, false
+			return false
 		}
 		if starNode != nil {
 			starNodeTemp = starNode
@@ -106,36 +332,25 @@ func (r *router) findRoute(method string, path string) (*matchInfo, bool) {
 		root = child
 
 		if isRegChild {
-			matchRegs := regexp.MustCompile(`:(.*?)\((.*)\)`)
-			regs := matchRegs.FindStringSubmatch(child.path)
-			// 注册时已校验,此处不校验
-			reg := regexp.MustCompile(regs[2])
-			matched := reg.MatchString(seg)
+			matched := child.regExpr.MatchString(seg)
 			if !matched && starNodeTemp != nil {
 				root = starNodeTemp
 				break
 			}
 			if !matched {
-				return nil, false
+				return false
 			}
-			if pathParams == nil {
-				pathParams = make(map[string]string)
-			}
-			pathParams[regs[1]] = seg
+			params = append(params, Param{Key: child.paramName, Value: seg})
 		}
 
 		if isParamChild {
-			if pathParams == nil {
-				pathParams = make(map[string]string)
-			}
-			pathParams[child.path[1:]] = seg
+			params = append(params, Param{Key: child.paramName, Value: seg})
 		}
 	}
 
-	return &matchInfo{
-		n:          root,
-		pathParams: pathParams,
-	}, true
+	info.n = root
+	info.pathParams = params
+	return true
 }
 
 type node struct {
@@ -154,65 +369,86 @@ type node struct {
 	// 正则匹配
 	regChild *node
 
+	// 参数路径和正则路径的参数名
+	paramName string
+	// 正则路径注册时编译好的正则表达式
+	regExpr *regexp.Regexp
+
 	// 代表用户注册的业务逻辑
 	handler HandleFunc
+	// 注册时的完整路径，只有 handler 不为 nil 的节点才有
+	route string
 }
 
-func (n *node) childOrCreate(path string) *node {
-	matchRegs := regexp.MustCompile(`:(.*?)\((.*)\)`)
-	regs := matchRegs.FindStringSubmatch(path)
+// 正则路径的格式 :name(expr)
+var regPathPattern = regexp.MustCompile(`:(.*?)\((.*)\)`)
+
+func (n *node) childOrCreate(path string) (*node, error) {
+	regs := regPathPattern.FindStringSubmatch(path)
 	if regs != nil {
 		if n.starChild != nil {
-			panic("web：不允许同时注册路径参数，通配符路径或正则路径，已有通配符路径")
+			return nil, &ErrMixedParamKinds{Existing: n.starChild.path, New: path}
 		}
 		if n.paramChild != nil {
-			panic("web：不允许同时注册路径参数，通配符路径或正则路径，已有参数路径")
+			return nil, &ErrMixedParamKinds{Existing: n.paramChild.path, New: path}
 		}
 		if n.regChild != nil && n.regChild.path != path {
-			panic(fmt.Sprintf("web: 路径冲突，已注册[%s]，重复注册[%s]", n.regChild.path, path))
+			return nil, &ErrConflict{Existing: n.regChild.path, New: path}
 		}
 		// 校验正则是否合法
-		regexp.MustCompile(regs[2])
+		expr, err := regexp.Compile(regs[2])
+		if err != nil {
+			return nil, &ErrBadRegex{Expr: regs[2], Err: err}
+		}
 		if n.regChild == nil {
 			n.regChild = &node{
-				path: path,
+				path:      path,
+				paramName: regs[1],
+				regExpr:   expr,
 			}
+		} else {
+			n.regChild = n.regChild.clone()
 		}
-		return n.regChild
+		return n.regChild, nil
 	}
 
 	if path[0] == ':' {
 		if n.starChild != nil {
-			panic("web：不允许同时注册路径参数，通配符路径或正则路径，已有通配符路径")
+			return nil, &ErrMixedParamKinds{Existing: n.starChild.path, New: path}
 		}
 		if n.regChild != nil {
-			panic("web：不允许同时注册路径参数，通配符路径或正则路径，已有正则路径")
+			return nil, &ErrMixedParamKinds{Existing: n.regChild.path, New: path}
 		}
 
 		if n.paramChild != nil && n.paramChild.path != path {
-			panic(fmt.Sprintf("web: 路径冲突，已注册[%s]，重复注册[%s]", n.paramChild.path, path))
+			return nil, &ErrConflict{Existing: n.paramChild.path, New: path}
 		}
 		if n.paramChild == nil {
 			n.paramChild = &node{
-				path: path,
+				path:      path,
+				paramName: path[1:],
 			}
+		} else {
+			n.paramChild = n.paramChild.clone()
 		}
-		return n.paramChild
+		return n.paramChild, nil
 	}
 
 	if path == "*" {
 		if n.paramChild != nil {
-			panic("web：不允许同时注册路径参数，通配符路径或正则路径，已有参数路径")
+			return nil, &ErrMixedParamKinds{Existing: n.paramChild.path, New: path}
 		}
 		if n.regChild != nil {
-			panic("web：不允许同时注册路径参数，通配符路径或正则路径，已有正则路径")
+			return nil, &ErrMixedParamKinds{Existing: n.regChild.path, New: path}
 		}
 		if n.starChild == nil {
 			n.starChild = &node{
 				path: path,
 			}
+		} else {
+			n.starChild = n.starChild.clone()
 		}
-		return n.starChild
+		return n.starChild, nil
 	}
 	if n.children == nil {
 		n.children = make(map[string]*node)
@@ -223,9 +459,70 @@ func (n *node) childOrCreate(path string) *node {
 		child = &node{
 			path: path,
 		}
-		n.children[path] = child
+	} else {
+		child = child.clone()
+	}
+	n.children[path] = child
+	return child, nil
+}
+
+// clone 复制节点用于写时复制，子节点仍然是共享的，
+// 所以 n 的子节点在修改之前也需要 clone
+func (n *node) clone() *node {
+	res := *n
+	if n.children != nil {
+		res.children = make(map[string]*node, len(n.children))
+		for k, v := range n.children {
+			res.children[k] = v
+		}
+	}
+	return &res
+}
+
+// childByPath 按照注册时的写法查找子节点，不做任何匹配
+func (n *node) childByPath(path string) *node {
+	for _, c := range []*node{n.regChild, n.paramChild, n.starChild} {
+		if c != nil && c.path == path {
+			return c
+		}
+	}
+	return n.children[path]
+}
+
+// setChild 用 child 替换同一个位置上原来的子节点
+func (n *node) setChild(child *node) {
+	switch {
+	case n.regChild != nil && n.regChild.path == child.path:
+		n.regChild = child
+	case n.paramChild != nil && n.paramChild.path == child.path:
+		n.paramChild = child
+	case n.starChild != nil && n.starChild.path == child.path:
+		n.starChild = child
+	default:
+		n.children[child.path] = child
 	}
-	return child
+}
+
+func (n *node) removeChild(child *node) {
+	switch child {
+	case n.regChild:
+		n.regChild = nil
+	case n.paramChild:
+		n.paramChild = nil
+	case n.starChild:
+		n.starChild = nil
+	default:
+		delete(n.children, child.path)
+		if len(n.children) == 0 {
+			n.children = nil
+		}
+	}
+}
+
+// empty 没有 handler 也没有子节点
+func (n *node) empty() bool {
+	return n.handler == nil && len(n.children) == 0 &&
+		n.regChild == nil && n.paramChild == nil && n.starChild == nil
 }
 
 // childOf 优先考虑静态查找，其次是参数路径，匹配不成功考虑通配符查找
@@ -234,7 +531,7 @@ func (n *node) childOrCreate(path string) *node {
 // 第三个返回值标记是否是正则匹配
 // 第四个返回值标记是否是参数路径
 // 第五个返回值标记是否找到
-func (n *node) childOf(path string) (*node, *node, bool, bool, bool) {
+func (n *node) childOf(path string, ignoreCase bool) (*node, *node, bool, bool, bool) {
 	if n.children == nil && n.regChild != nil {
 		return n.regChild, n.starChild, true, false, true
 	}
@@ -245,6 +542,9 @@ func (n *node) childOf(path string) (*node, *node, bool, bool, bool) {
 		return n.starChild, n.starChild, false, false, n.starChild != nil
 	}
 	child, ok := n.children[path]
+	if !ok && ignoreCase {
+		child, ok = n.childFold(path)
+	}
 	if !ok && n.regChild != nil {
 		return n.regChild, n.starChild, true, false, true
 	}
@@ -257,7 +557,18 @@ func (n *node) childOf(path string) (*node, *node, bool, bool, bool) {
 	return child, n.starChild, false, false, ok
 }
 
+// childFold 忽略大小写查找静态子节点，有多个的时候取字典序最小的，保证结果稳定
+func (n *node) childFold(path string) (*node, bool) {
+	var res *node
+	for key, child := range n.children {
+		if strings.EqualFold(key, path) && (res == nil || key < res.path) {
+			res = child
+		}
+	}
+	return res, res != nil
+}
+
 type matchInfo struct {
 	n          *node
-	pathParams map[string]string
+	pathParams []Param
 }
@@ -0,0 +1,16 @@
+package web_test
+
+import (
+	"testing"
+
+	"geektimeGoClass/web"
+	"geektimeGoClass/web/routertest"
+)
+
+func TestRouter_Conformance(t *testing.T) {
+	routertest.Run(t, web.NewTreeRouter)
+}
+
+func TestRadixRouter_Conformance(t *testing.T) {
+	routertest.Run(t, web.NewRadixRouter)
+}
@@ -110,65 +110,33 @@ func TestRouter_AddRoute(t *testing.T) {
 
 	// 3. 断言
 	// HandleFunc 不能用assert
-	wantRouter := &router{
-		trees: map[string]*node{
-			http.MethodGet: &node{
-				path:    "/",
-				handler: mockHandler,
-				children: map[string]*node{
-					"user": &node{
-						path:    "user",
-						handler: mockHandler,
-						children: map[string]*node{
-							"home": &node{
-								path:     "home",
-								children: nil,
-								handler:  mockHandler,
-							},
-						},
-					},
-					"order": &node{
-						path: "order",
-						children: map[string]*node{
-							"detail": &node{
-								path:     "detail",
-								children: nil,
-								handler:  mockHandler,
-								paramChild: &node{
-									path:    ":id",
-									handler: mockHandler,
-								},
-							},
-						},
-						starChild: &node{
-							path:    "*",
-							handler: mockHandler,
-						},
-					},
-					"param": {
-						path: "param",
-						paramChild: &node{
-							path: ":id",
-							starChild: &node{
-								path:    "*",
-								handler: mockHandler,
-							},
-							children: map[string]*node{"detail": {path: "detail", handler: mockHandler}},
+	wantTrees := map[string]*node{
+		http.MethodGet: &node{
+			path:    "/",
+			handler: mockHandler,
+			children: map[string]*node{
+				"user": &node{
+					path:    "user",
+					handler: mockHandler,
+					children: map[string]*node{
+						"home": &node{
+							path:     "home",
+							children: nil,
 							handler:  mockHandler,
 						},
 					},
 				},
-				starChild: &node{
-					path:    "*",
-					handler: mockHandler,
+				"order": &node{
+					path: "order",
 					children: map[string]*node{
-						"abc": &node{
-							path: "abc",
-							starChild: &node{
-								path:    "*",
+						"detail": &node{
+							path:     "detail",
+							children: nil,
+							handler:  mockHandler,
+							paramChild: &node{
+								path:    ":id",
 								handler: mockHandler,
 							},
-							handler: mockHandler,
 						},
 					},
 					starChild: &node{
@@ -176,57 +144,87 @@ func TestRouter_AddRoute(t *testing.T) {
 						handler: mockHandler,
 					},
 				},
+				"param": {
+					path: "param",
+					paramChild: &node{
+						path: ":id",
+						starChild: &node{
+							path:    "*",
+							handler: mockHandler,
+						},
+						children: map[string]*node{"detail": {path: "detail", handler: mockHandler}},
+						handler:  mockHandler,
+					},
+				},
 			},
-			http.MethodPost: &node{
-				path:    "/",
+			starChild: &node{
+				path:    "*",
 				handler: mockHandler,
 				children: map[string]*node{
-					"login": &node{
-						path:     "login",
-						children: nil,
-						handler:  mockHandler,
-					},
-					"order": &node{
-						path: "order",
-						children: map[string]*node{
-							"create": &node{
-								path:     "create",
-								children: nil,
-								handler:  mockHandler,
-							},
+					"abc": &node{
+						path: "abc",
+						starChild: &node{
+							path:    "*",
+							handler: mockHandler,
 						},
+						handler: mockHandler,
 					},
 				},
-				paramChild: &node{
-					path:    ":id",
+				starChild: &node{
+					path:    "*",
 					handler: mockHandler,
 				},
 			},
-			http.MethodDelete: &node{
-				path: "/",
-				children: map[string]*node{
-					"reg": {
-						path: "reg",
-						regChild: &node{
-							path:    ":id(.*)",
-							handler: mockHandler,
-						},
-					},
+		},
+		http.MethodPost: &node{
+			path:    "/",
+			handler: mockHandler,
+			children: map[string]*node{
+				"login": &node{
+					path:     "login",
+					children: nil,
+					handler:  mockHandler,
 				},
-				regChild: &node{
-					path: ":name(^.+$)",
+				"order": &node{
+					path: "order",
 					children: map[string]*node{
-						"abc": {
-							path:    "abc",
-							handler: mockHandler,
+						"create": &node{
+							path:     "create",
+							children: nil,
+							handler:  mockHandler,
 						},
 					},
 				},
 			},
+			paramChild: &node{
+				path:    ":id",
+				handler: mockHandler,
+			},
+		},
+		http.MethodDelete: &node{
+			path: "/",
+			children: map[string]*node{
+				"reg": {
+					path: "reg",
+					regChild: &node{
+						path:    ":id(.*)",
+						handler: mockHandler,
+					},
+				},
+			},
+			regChild: &node{
+				path: ":name(^.+$)",
+				children: map[string]*node{
+					"abc": {
+						path:    "abc",
+						handler: mockHandler,
+					},
+				},
+			},
 		},
 	}
 
-	msg, ok := r.equal(wantRouter)
+	msg, ok := r.equal(wantTrees)
 	assert.True(t, ok, msg)
 
 	r = newRouter()
@@ -336,15 +334,15 @@ func TestRouter_AddRoute(t *testing.T) {
 
 // string 返回错误信息，帮助定位问题
 // bool 是否相等
-func (r *router) equal(y *router) (string, bool) {
+func (r *router) equal(y map[string]*node) (string, bool) {
 	// 非空判断
 	if r == nil || y == nil {
 		return fmt.Sprintf("空的路径树"), false
 	}
 
 	// 便利
-	for k, v := range r.trees {
-		dst, ok := y.trees[k]
+	for k, v := range r.loadTrees() {
+		dst, ok := y[k]
 		if !ok {
 			return fmt.Sprintf("找不到对应的 http method"), false
 		}
@@ -632,8 +630,8 @@ func TestRouter_FindRoute(t *testing.T) {
 						handler: mockHandler,
 					},
 				},
-				pathParams: map[string]string{
-					"username": "why",
+				pathParams: []Param{
+					{Key: "username", Value: "why"},
 				},
 			},
 		},
@@ -648,8 +646,8 @@ func TestRouter_FindRoute(t *testing.T) {
 					path:    "detail",
 					handler: mockHandler,
 				},
-				pathParams: map[string]string{
-					"username": "why",
+				pathParams: []Param{
+					{Key: "username", Value: "why"},
 				},
 			},
 		},
@@ -664,8 +662,8 @@ func TestRouter_FindRoute(t *testing.T) {
 					path:    "*",
 					handler: mockHandler,
 				},
-				pathParams: map[string]string{
-					"username": "why",
+				pathParams: []Param{
+					{Key: "username", Value: "why"},
 				},
 			},
 		},
@@ -680,8 +678,8 @@ func TestRouter_FindRoute(t *testing.T) {
 					path:    ":id(.*)",
 					handler: mockHandler,
 				},
-				pathParams: map[string]string{
-					"id": "why",
+				pathParams: []Param{
+					{Key: "id", Value: "why"},
 				},
 			},
 		},
@@ -696,8 +694,8 @@ func TestRouter_FindRoute(t *testing.T) {
 					path:    "home",
 					handler: mockHandler,
 				},
-				pathParams: map[string]string{
-					"id": "123",
+				pathParams: []Param{
+					{Key: "id", Value: "123"},
 				},
 			},
 		},
@@ -724,3 +722,80 @@ func TestRouter_FindRoute(t *testing.T) {
 	}
 
 }
+
+func TestRouter_RemoveRoute(t *testing.T) {
+	var mockHandler HandleFunc = func(ctx *Context) {}
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/", mockHandler)
+	r.addRoute(http.MethodGet, "/user/:id/detail", mockHandler)
+	r.addRoute(http.MethodGet, "/user/:id", mockHandler)
+	r.addRoute(http.MethodGet, "/order/:id([0-9]+)/detail", mockHandler)
+	r.addRoute(http.MethodGet, "/static/*", mockHandler)
+	before := r.loadTrees()
+
+	// 不存在的路由
+	assert.False(t, r.RemoveRoute(http.MethodPost, "/user/:id"))
+	assert.False(t, r.RemoveRoute(http.MethodGet, "/user"))
+	assert.False(t, r.RemoveRoute(http.MethodGet, "/user/:name"))
+	assert.False(t, r.RemoveRoute(http.MethodGet, "/order/:id([0-9]+)"))
+
+	assert.True(t, r.RemoveRoute(http.MethodGet, "/user/:id/detail"))
+	assert.True(t, r.RemoveRoute(http.MethodGet, "/order/:id([0-9]+)/detail"))
+	assert.True(t, r.RemoveRoute(http.MethodGet, "/static/*"))
+	assert.True(t, r.RemoveRoute(http.MethodGet, "/"))
+	assert.False(t, r.RemoveRoute(http.MethodGet, "/"))
+
+	// 没有 handler 的节点被清理掉了，/user/:id 还在
+	msg, ok := r.equal(map[string]*node{
+		http.MethodGet: {
+			path: "/",
+			children: map[string]*node{
+				"user": {
+					path: "user",
+					paramChild: &node{
+						path:    ":id",
+						handler: mockHandler,
+					},
+				},
+			},
+		},
+	})
+	assert.True(t, ok, msg)
+
+	// 删除之后可以重新注册
+	assert.NotPanics(t, func() {
+		r.addRoute(http.MethodGet, "/static/:name", mockHandler)
+	})
+
+	// 旧版本的路径树没有被修改
+	info := &matchInfo{}
+	old := &router{}
+	old.trees.Store(before)
+	assert.True(t, old.match(http.MethodGet, "/user/123/detail", info, false))
+	assert.NotNil(t, info.n.handler)
+}
+
+func TestRouter_AddRoute_ErrorKeepsTree(t *testing.T) {
+	var mockHandler HandleFunc = func(ctx *Context) {}
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/a/:id", mockHandler)
+	var badRegex *ErrBadRegex
+	assert.ErrorAs(t, r.AddRoute(http.MethodGet, "/a/:id/b/:name(.(.*)", mockHandler), &badRegex)
+	assert.Equal(t, ".(.*", badRegex.Expr)
+	// 注册失败不会留下半截的节点
+	msg, ok := r.equal(map[string]*node{
+		http.MethodGet: {
+			path: "/",
+			children: map[string]*node{
+				"a": {
+					path: "a",
+					paramChild: &node{
+						path:    ":id",
+						handler: mockHandler,
+					},
+				},
+			},
+		},
+	})
+	assert.True(t, ok, msg)
+}
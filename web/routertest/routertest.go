@@ -0,0 +1,220 @@
+// Package routertest 是 web.Router 的一致性测试，
+// 所有 Router 的实现都应该通过 Run，包括框架自带的 NewTreeRouter 和 NewRadixRouter
+package routertest
+
+import (
+	"net/http"
+	"testing"
+
+	"geektimeGoClass/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run 用 newRouter 创建的 Router 跑一遍所有的用例
+func Run(t *testing.T, newRouter func() web.Router) {
+	t.Run("AddRoute", func(t *testing.T) {
+		testAddRoute(t, newRouter)
+	})
+	t.Run("FindRoute", func(t *testing.T) {
+		testFindRoute(t, newRouter)
+	})
+	t.Run("Routes", func(t *testing.T) {
+		testRoutes(t, newRouter)
+	})
+}
+
+func testAddRoute(t *testing.T, newRouter func() web.Router) {
+	var mockHandler web.HandleFunc = func(ctx *web.Context) {}
+	var (
+		conflict *web.ErrConflict
+		mixed    *web.ErrMixedParamKinds
+		badRegex *web.ErrBadRegex
+	)
+	testCases := []struct {
+		existing string
+		path     string
+		wantErr  string
+		// wantIs 是 errors.Is 的目标，wantAs 是 errors.As 的目标
+		wantIs error
+		wantAs any
+	}{
+		{path: "", wantErr: "web：路径不能为空字符串", wantIs: web.ErrEmptyPath},
+		{path: "user", wantErr: "web：路径必须以 / 开头", wantIs: web.ErrPathNoLeadingSlash},
+		{path: "/user/root/", wantErr: "web：路径不能以 / 结尾", wantIs: web.ErrPathTrailingSlash},
+		{path: "/user//root", wantErr: "web：路径不能出现连续的 /", wantIs: web.ErrPathDoubleSlash},
+		{path: "//a/b", wantErr: "web：路径不能出现连续的 /", wantIs: web.ErrPathDoubleSlash},
+		{existing: "/", path: "/", wantErr: "web: 路径冲突，重复注册[/]", wantAs: &conflict},
+		{existing: "/a", path: "/a", wantErr: "web: 路径冲突，重复注册[/a]", wantAs: &conflict},
+		{existing: "/*", path: "/*", wantErr: "web: 路径冲突，重复注册[/*]", wantAs: &conflict},
+		{existing: "/:id", path: "/:id", wantErr: "web: 路径冲突，重复注册[/:id]", wantAs: &conflict},
+		{existing: "/:id(.*)", path: "/:id(.*)", wantErr: "web: 路径冲突，重复注册[/:id(.*)]", wantAs: &conflict},
+		{existing: "/a/*", path: "/a/:id", wantErr: "web：不允许同时注册路径参数，通配符路径或正则路径，已有通配符路径", wantAs: &mixed},
+		{existing: "/a/*", path: "/a/:id(.*)", wantErr: "web：不允许同时注册路径参数，通配符路径或正则路径，已有通配符路径", wantAs: &mixed},
+		{existing: "/a/:id", path: "/a/*", wantErr: "web：不允许同时注册路径参数，通配符路径或正则路径，已有参数路径", wantAs: &mixed},
+		{existing: "/a/:id", path: "/a/:id(.*)", wantErr: "web：不允许同时注册路径参数，通配符路径或正则路径，已有参数路径", wantAs: &mixed},
+		{existing: "/a/:id(.*)", path: "/a/*", wantErr: "web：不允许同时注册路径参数，通配符路径或正则路径，已有正则路径", wantAs: &mixed},
+		{existing: "/a/:id(.*)", path: "/a/:id", wantErr: "web：不允许同时注册路径参数，通配符路径或正则路径，已有正则路径", wantAs: &mixed},
+		{existing: "/a/:id", path: "/a/:age/abc", wantErr: "web: 路径冲突，已注册[:id]，重复注册[:age]", wantAs: &conflict},
+		{existing: "/a/:id(.*)", path: "/a/:age(.*)/abc", wantErr: "web: 路径冲突，已注册[:id(.*)]，重复注册[:age(.*)]", wantAs: &conflict},
+		{path: "/a/:age(.(.*)", wantErr: "regexp: Compile(`.(.*`): error parsing regexp: missing closing ): `.(.*`", wantAs: &badRegex},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.existing+" "+tc.path, func(t *testing.T) {
+			r := newRouter()
+			if tc.existing != "" {
+				require.NoError(t, r.AddRoute(http.MethodGet, tc.existing, mockHandler))
+			}
+			err := r.AddRoute(http.MethodGet, tc.path, mockHandler)
+			assert.EqualError(t, err, tc.wantErr)
+			if tc.wantIs != nil {
+				assert.ErrorIs(t, err, tc.wantIs)
+			}
+			if tc.wantAs != nil {
+				assert.ErrorAs(t, err, tc.wantAs)
+			}
+		})
+	}
+
+	// 不同的 HTTP 方法互不影响
+	r := newRouter()
+	require.NoError(t, r.AddRoute(http.MethodGet, "/a", mockHandler))
+	assert.NoError(t, r.AddRoute(http.MethodPost, "/a", mockHandler))
+
+	// 出错的时候不能留下注册了一半的路由，后面的注册不受影响
+	for _, path := range []string{"/a/:name", "/a/*"} {
+		r = newRouter()
+		assert.Error(t, r.AddRoute(http.MethodGet, "/a/:id/:x(bad[)", mockHandler))
+		assert.Error(t, r.AddRoute(http.MethodGet, "/a/:id/b/*/:x(bad[)", mockHandler))
+		assert.Empty(t, r.Routes())
+		m := &web.Match{}
+		assert.True(t, !r.FindRoute(http.MethodGet, "/a/123", m) || m.Handler == nil)
+		assert.NoError(t, r.AddRoute(http.MethodGet, path, mockHandler), path)
+	}
+}
+
+var findRoutes = []struct {
+	method string
+	path   string
+}{
+	{method: http.MethodDelete, path: "/"},
+	{method: http.MethodPut, path: "/login"},
+	{method: http.MethodGet, path: "/order/detail"},
+	{method: http.MethodGet, path: "/order/details"},
+	{method: http.MethodGet, path: "/star/*"},
+	{method: http.MethodGet, path: "/star/*/abc"},
+	{method: http.MethodGet, path: "/star/a/*"},
+	{method: http.MethodGet, path: "/star/a/b/c"},
+	{method: http.MethodPost, path: "/params/:username"},
+	{method: http.MethodPost, path: "/params/:username/detail"},
+	{method: http.MethodPost, path: "/params/:username/*"},
+	{method: http.MethodPost, path: "/params/abc"},
+	{method: http.MethodPost, path: "/reg/:id(.*)"},
+	{method: http.MethodPost, path: "/:id([0-9]+)/home"},
+}
+
+func testFindRoute(t *testing.T, newRouter func() web.Router) {
+	r := newRouter()
+	// 每个路由的 handler 记录自己的路径，用来判断命中的是不是正确的 handler
+	var hit string
+	for _, route := range findRoutes {
+		route := route
+		require.NoError(t, r.AddRoute(route.method, route.path, func(ctx *web.Context) {
+			hit = route.path
+		}))
+	}
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		// 为空表示没有命中，或者命中了但是没有 handler
+		wantPattern string
+		wantParams  []web.Param
+	}{
+		{name: "method not found", method: http.MethodOptions, path: "/"},
+		{name: "root", method: http.MethodDelete, path: "/", wantPattern: "/"},
+		{name: "login", method: http.MethodPut, path: "/login", wantPattern: "/login"},
+		{name: "order detail", method: http.MethodGet, path: "/order/detail", wantPattern: "/order/detail"},
+		{name: "order details", method: http.MethodGet, path: "/order/details", wantPattern: "/order/details"},
+		{name: "order", method: http.MethodGet, path: "/order"},
+		{name: "prefix of segment", method: http.MethodGet, path: "/order/deta"},
+		{name: "star abc", method: http.MethodGet, path: "/star/abc", wantPattern: "/star/*"},
+		{name: "star abc abc", method: http.MethodGet, path: "/star/abc/abc", wantPattern: "/star/*/abc"},
+		{name: "star a b c", method: http.MethodGet, path: "/star/a/b/c", wantPattern: "/star/a/b/c"},
+		{name: "star a b c d", method: http.MethodGet, path: "/star/a/b/c/d", wantPattern: "/star/a/*"},
+		{
+			name: "params username", method: http.MethodPost, path: "/params/why",
+			wantPattern: "/params/:username",
+			wantParams:  []web.Param{{Key: "username", Value: "why"}},
+		},
+		{name: "params static first", method: http.MethodPost, path: "/params/abc", wantPattern: "/params/abc"},
+		{
+			name: "params prefix of static", method: http.MethodPost, path: "/params/ab",
+			wantPattern: "/params/:username",
+			wantParams:  []web.Param{{Key: "username", Value: "ab"}},
+		},
+		{
+			name: "params username detail", method: http.MethodPost, path: "/params/why/detail",
+			wantPattern: "/params/:username/detail",
+			wantParams:  []web.Param{{Key: "username", Value: "why"}},
+		},
+		{
+			name: "params username star", method: http.MethodPost, path: "/params/why/abc",
+			wantPattern: "/params/:username/*",
+			wantParams:  []web.Param{{Key: "username", Value: "why"}},
+		},
+		{
+			name: "reg id any", method: http.MethodPost, path: "/reg/why",
+			wantPattern: "/reg/:id(.*)",
+			wantParams:  []web.Param{{Key: "id", Value: "why"}},
+		},
+		{
+			name: "reg id number", method: http.MethodPost, path: "/123/home",
+			wantPattern: "/:id([0-9]+)/home",
+			wantParams:  []web.Param{{Key: "id", Value: "123"}},
+		},
+		{name: "reg id not number", method: http.MethodPost, path: "/why/home"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &web.Match{}
+			found := r.FindRoute(tc.method, tc.path, m)
+			if tc.wantPattern == "" {
+				assert.True(t, !found || m.Handler == nil)
+				return
+			}
+			assert.True(t, found)
+			assert.Equal(t, tc.wantPattern, m.Pattern)
+			assert.Equal(t, tc.wantParams, m.Params)
+			hit = ""
+			m.Handler(nil)
+			assert.Equal(t, tc.wantPattern, hit)
+		})
+	}
+
+	// 路径参数追加在 m.Params 后面
+	m := &web.Match{Params: []web.Param{{Key: "host", Value: "abc"}}}
+	assert.True(t, r.FindRoute(http.MethodPost, "/params/why", m))
+	assert.Equal(t, []web.Param{{Key: "host", Value: "abc"}, {Key: "username", Value: "why"}}, m.Params)
+}
+
+func testRoutes(t *testing.T, newRouter func() web.Router) {
+	r := newRouter()
+	var mockHandler web.HandleFunc = func(ctx *web.Context) {}
+	for _, route := range findRoutes {
+		require.NoError(t, r.AddRoute(route.method, route.path, mockHandler))
+	}
+	routes := r.Routes()
+	assert.Len(t, routes, len(findRoutes))
+	for _, route := range findRoutes {
+		found := false
+		for _, ri := range routes {
+			if ri.Method == route.method && ri.Pattern == route.path {
+				found = ri.Handler != nil
+				break
+			}
+		}
+		assert.True(t, found, "%s %s", route.method, route.path)
+	}
+}
@@ -0,0 +1,203 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Routes 返回所有注册的路由，按照 HTTP 方法和路径排序，
+// 可以在启动的时候打印出来，或者在测试里面断言
+// 按域名注册的路由排在默认路由后面
+func (h *HttpServer) Routes() []RouteInfo {
+	res := h.router.Routes()
+	for _, hr := range h.loadHosts() {
+		for _, r := range hr.router.Routes() {
+			r.Host = hr.pattern
+			res = append(res, r)
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, r := range res {
+		res[i].handlerName = h.handlerNames[routeKey{host: r.Host, method: r.Method, pattern: r.Pattern}]
+	}
+	return res
+}
+
+// routeKey 区分不同域名下面的路由
+type routeKey struct {
+	host    string
+	method  string
+	pattern string
+}
+
+// HandlerName 返回 handler 的函数名，比如 main.(*UserController).GetUser-fm。
+// 通过 HttpServer 注册的路由返回注册时传入的 handler 的名字，
+// 而不是套上 middleware 之后的函数的名字
+func (r RouteInfo) HandlerName() string {
+	if r.handlerName != "" {
+		return r.handlerName
+	}
+	return funcName(r.Handler)
+}
+
+func funcName(handleFunc HandleFunc) string {
+	if handleFunc == nil {
+		return ""
+	}
+	fn := runtime.FuncForPC(reflect.ValueOf(handleFunc).Pointer())
+	if fn == nil {
+		return ""
+	}
+	return fn.Name()
+}
+
+// ParamNames 返回路径参数和正则参数的参数名，按照出现的顺序
+func (r RouteInfo) ParamNames() []string {
+	var res []string
+	for _, seg := range strings.Split(r.Pattern, "/") {
+		if regs := regPathPattern.FindStringSubmatch(seg); regs != nil {
+			res = append(res, regs[1])
+		} else if strings.HasPrefix(seg, ":") {
+			res = append(res, seg[1:])
+		}
+	}
+	return res
+}
+
+func (r RouteInfo) String() string {
+	return fmt.Sprintf("%s %s%s -> %s", r.Method, r.Host, r.Pattern, r.HandlerName())
+}
+
+// DumpRoutes 把路由按照路径树的形式打印出来，比如：
+//
+//	GET
+//	/  main.Index
+//	├── order
+//	│   └── :id  main.OrderDetail
+//	└── user  main.User
+func (h *HttpServer) DumpRoutes(w io.Writer) error {
+	routes := h.Routes()
+	// 每个域名的每个 HTTP 方法一棵树
+	var keys []string
+	trees := map[string]*dumpNode{}
+	for _, r := range routes {
+		key := r.Method
+		if r.Host != "" {
+			key = r.Method + " " + r.Host
+		}
+		root, ok := trees[key]
+		if !ok {
+			root = &dumpNode{seg: "/"}
+			trees[key] = root
+			keys = append(keys, key)
+		}
+		n := root
+		if r.Pattern != "/" {
+			for _, seg := range strings.Split(r.Pattern[1:], "/") {
+				n = n.childOrCreate(seg)
+			}
+		}
+		n.handler = r.HandlerName()
+	}
+
+	var sb strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(key)
+		sb.WriteByte('\n')
+		root := trees[key]
+		root.writeLine(&sb)
+		root.dumpChildren(&sb, "")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+type dumpNode struct {
+	seg      string
+	handler  string
+	children []*dumpNode
+}
+
+func (n *dumpNode) childOrCreate(seg string) *dumpNode {
+	for _, c := range n.children {
+		if c.seg == seg {
+			return c
+		}
+	}
+	c := &dumpNode{seg: seg}
+	n.children = append(n.children, c)
+	sort.Slice(n.children, func(i, j int) bool {
+		return n.children[i].seg < n.children[j].seg
+	})
+	return c
+}
+
+func (n *dumpNode) writeLine(sb *strings.Builder) {
+	sb.WriteString(n.seg)
+	if n.handler != "" {
+		sb.WriteString("  ")
+		sb.WriteString(n.handler)
+	}
+	sb.WriteByte('\n')
+}
+
+func (n *dumpNode) dumpChildren(sb *strings.Builder, indent string) {
+	for i, c := range n.children {
+		last := i == len(n.children)-1
+		sb.WriteString(indent)
+		if last {
+			sb.WriteString("└── ")
+		} else {
+			sb.WriteString("├── ")
+		}
+		c.writeLine(sb)
+		if last {
+			c.dumpChildren(sb, indent+"    ")
+		} else {
+			c.dumpChildren(sb, indent+"│   ")
+		}
+	}
+}
+
+// standardMethods 标准的 HTTP 方法
+var standardMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// Methods 返回注册了 r 的路径的 HTTP 方法，忽略 r.Method 本身，
+// 按照处理请求时同样的规则查找，包括域名、结尾的 /、大小写和转义的设置，比如用来回应 CORS 的预检请求。
+// 只会检查标准的 HTTP 方法
+func (h *HttpServer) Methods(r *http.Request) []string {
+	path := r.URL.Path
+	if h.useRawPath {
+		path = r.URL.EscapedPath()
+	}
+	if cleaned := cleanPath(path); cleaned != path {
+		// 和 serve 一样，TrailingSlashStrict 的时候不规范的路径不会命中任何路由
+		if h.trailingSlash == TrailingSlashStrict {
+			return nil
+		}
+		path = cleaned
+	}
+	var (
+		res []string
+		m   Match
+	)
+	for _, method := range standardMethods {
+		m = Match{Params: m.Params[:0]}
+		if ok, _ := h.lookup(r.Host, method, path, &m); ok {
+			res = append(res, method)
+		}
+	}
+	return res
+}
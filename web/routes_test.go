@@ -0,0 +1,133 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func userDetail(ctx *Context) {}
+
+func orderDetail(ctx *Context) {}
+
+func index(ctx *Context) {}
+
+func TestHttpServer_Routes(t *testing.T) {
+	h := NewHTTPServer()
+	h.Get("/user/:id/order/:oid([0-9]+)", userDetail)
+	h.Get("/", index)
+	h.Post("/order/*", orderDetail)
+
+	routes := h.Routes()
+	require.Len(t, routes, 3)
+
+	assert.Equal(t, http.MethodGet, routes[0].Method)
+	assert.Equal(t, "/", routes[0].Pattern)
+	assert.Equal(t, "geektimeGoClass/web.index", routes[0].HandlerName())
+	assert.Nil(t, routes[0].ParamNames())
+
+	assert.Equal(t, "/user/:id/order/:oid([0-9]+)", routes[1].Pattern)
+	assert.Equal(t, []string{"id", "oid"}, routes[1].ParamNames())
+	assert.Equal(t, "GET /user/:id/order/:oid([0-9]+) -> geektimeGoClass/web.userDetail", routes[1].String())
+
+	assert.Equal(t, http.MethodPost, routes[2].Method)
+	assert.Equal(t, "/order/*", routes[2].Pattern)
+	assert.Nil(t, routes[2].ParamNames())
+}
+
+func TestHttpServer_Routes_HandlerName(t *testing.T) {
+	h := NewHTTPServer()
+	// 套上 middleware 之后还是显示原来的 handler
+	h.Get("/user/:id", userDetail, Timeout(time.Second))
+	h.Host("api.example.com").Get("/order/:id", orderDetail, logMiddleware("a"))
+	require.NoError(t, h.LoadRoutes(&RouteConfig{Routes: []RouteSpec{
+		{Path: "/", Methods: []string{http.MethodGet}, Handler: "index", Middlewares: []string{"a"}, Deprecated: true},
+	}}, NewRegistry().Handler("index", index).Middleware("a", logMiddleware("a"))))
+
+	var names []string
+	for _, r := range h.Routes() {
+		names = append(names, r.String())
+	}
+	assert.Equal(t, []string{
+		"GET / -> geektimeGoClass/web.index",
+		"GET /user/:id -> geektimeGoClass/web.userDetail",
+		"GET api.example.com/order/:id -> geektimeGoClass/web.orderDetail",
+	}, names)
+
+	// 删除之后重新注册，使用新的 handler 的名字
+	require.True(t, h.RemoveRoute(http.MethodGet, "/user/:id"))
+	h.Get("/user/:id", index, Timeout(time.Second))
+	assert.Equal(t, "geektimeGoClass/web.index", h.Routes()[1].HandlerName())
+}
+
+func TestHttpServer_DumpRoutes(t *testing.T) {
+	h := NewHTTPServer()
+	h.Get("/", index)
+	h.Get("/user/:id", userDetail)
+	h.Get("/user/:id/order/:oid([0-9]+)", orderDetail)
+	h.Get("/order/detail", orderDetail)
+	h.Post("/order/*", orderDetail)
+
+	sb := &strings.Builder{}
+	require.NoError(t, h.DumpRoutes(sb))
+	assert.Equal(t, `GET
+/  geektimeGoClass/web.index
+├── order
+│   └── detail  geektimeGoClass/web.orderDetail
+└── user
+    └── :id  geektimeGoClass/web.userDetail
+        └── order
+            └── :oid([0-9]+)  geektimeGoClass/web.orderDetail
+
+POST
+/
+└── order
+    └── *  geektimeGoClass/web.orderDetail
+`, sb.String())
+}
+
+func TestHttpServer_Methods(t *testing.T) {
+	h := NewHTTPServer(WithCaseInsensitive(CaseInsensitiveServe))
+	h.Get("/user/:id", userDetail)
+	h.Post("/user/:id", userDetail)
+	h.Handle(http.MethodDelete, "/user/123", userDetail)
+	h.Get("/order/*", orderDetail)
+	h.Host("api.example.com").Handle(http.MethodPut, "/user/:id", userDetail)
+
+	testCases := []struct {
+		name string
+		host string
+		path string
+		want []string
+	}{
+		{name: "param", path: "/user/abc", want: []string{http.MethodGet, http.MethodPost}},
+		{name: "static", path: "/user/123", want: []string{http.MethodGet, http.MethodPost, http.MethodDelete}},
+		{name: "unclean", path: "/user//abc/", want: []string{http.MethodGet, http.MethodPost}},
+		{name: "ignore case", path: "/ORDER/abc", want: []string{http.MethodGet}},
+		{name: "host", host: "api.example.com", path: "/user/abc", want: []string{http.MethodGet, http.MethodPost, http.MethodPut}},
+		{name: "not found", path: "/missing"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodOptions, "http://example.com"+tc.path, nil)
+			require.NoError(t, err)
+			if tc.host != "" {
+				req.Host = tc.host
+			}
+			assert.Equal(t, tc.want, h.Methods(req))
+		})
+	}
+
+	// 路径不规范的请求在 TrailingSlashStrict 下会返回 404，所以也没有可用的方法
+	strict := NewHTTPServer(WithTrailingSlashPolicy(TrailingSlashStrict))
+	strict.Get("/x", userDetail)
+	for path, want := range map[string][]string{"/x": {http.MethodGet}, "/x/": nil, "//x": nil} {
+		req, err := http.NewRequest(http.MethodOptions, "http://example.com"+path, nil)
+		require.NoError(t, err)
+		assert.Equal(t, want, strict.Methods(req), path)
+	}
+}
@@ -1,8 +1,18 @@
 package web
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type HandleFunc func(ctx *Context)
@@ -14,6 +24,12 @@ type Server interface {
 	http.Handler
 	Start(add string) error
 
+	// Serve 在一个或多个 listener 上同时提供服务
+	Serve(ls ...net.Listener) error
+
+	// Shutdown 优雅退出，所有 listener 一起关闭
+	Shutdown(ctx context.Context) error
+
 	// AddRoute 增加路由注册的功能
 	// method 是 HTTP 方法
 	// path 是路由
@@ -22,48 +38,354 @@ type Server interface {
 }
 
 type HttpServer struct {
-	*router
+	router Router
+
+	mu sync.Mutex
+	// 所有 listener 共用一个 http.Server，这样 Shutdown 可以一次性关闭全部
+	srv *http.Server
+	// 正在提供服务的 listener，Upgrade 的时候交给新进程
+	listeners []net.Listener
+
+	// 复用 Context，减少每个请求的内存分配
+	ctxPool sync.Pool
+
+	// 路由的名字到注册路径的映射
+	names map[string]*namedRoute
+	// 注册时传入的 handler 的名字，Routes 返回的是套上 middleware 之后的 handler
+	handlerNames map[routeKey]string
+
+	// 按域名注册的路由，存放 []*hostRoutes，和路径树一样写时复制
+	hosts atomic.Value
+
+	// 请求路径不规范时的处理方式
+	trailingSlash TrailingSlashPolicy
+	// 是否忽略静态路径的大小写
+	caseInsensitive CaseInsensitivePolicy
+	// 是否使用转义之后的路径查找路由
+	useRawPath bool
+
+	// 请求 ID 使用的请求头和响应头，为空表示不使用请求 ID
+	requestIDHeader string
+	requestIDGen    func() string
+
+	// Use 注册的 middleware，handler 是套上这些 middleware 之后的 serve
+	mdls    []Middleware
+	handler HandleFunc
+}
+
+// CaseInsensitivePolicy 静态路径大小写不一致时的处理方式
+// 只对实现了 CaseInsensitiveRouter 的 Router 生效
+type CaseInsensitivePolicy int
+
+const (
+	// CaseSensitive 默认区分大小写
+	CaseSensitive CaseInsensitivePolicy = iota
+	// CaseInsensitiveServe 忽略大小写，直接处理请求
+	CaseInsensitiveServe
+	// CaseInsensitiveRedirect 忽略大小写，重定向到注册时的大小写
+	CaseInsensitiveRedirect
+)
+
+// TrailingSlashPolicy 请求路径带有结尾的 /、连续的 /、. 或者 .. 时的处理方式
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashLenient 默认的处理方式，按照 path.Clean 规范化之后再查找路由，
+	// 比如 /login/ 和 //login 都会命中 /login
+	TrailingSlashLenient TrailingSlashPolicy = iota
+	// TrailingSlashStrict 路径必须是规范的，否则返回 404
+	TrailingSlashStrict
+	// TrailingSlashRedirect 如果规范化之后的路径能够命中路由，就重定向过去，
+	// GET 和 HEAD 请求用 301，其它的用 308 保证请求方法和 body 不变
+	TrailingSlashRedirect
+)
+
+type HTTPServerOption func(server *HttpServer)
+
+func NewHTTPServer(opts ...HTTPServerOption) *HttpServer {
+	h := &HttpServer{
+		router:          NewTreeRouter(),
+		requestIDHeader: textproto.CanonicalMIMEHeaderKey(DefaultRequestIDHeader),
+		requestIDGen:    newRequestIDGenerator(),
+		ctxPool: sync.Pool{
+			New: func() any {
+				return &Context{}
+			},
+		},
+	}
+	h.handler = h.serve
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Use 注册全局的 middleware，按照注册的顺序执行，第一个在最外层。
+// 它们包住路由查找和业务逻辑，所以没有命中路由的请求也会经过它们，
+// 这个时候 Context 里面没有路径参数。只能在服务启动之前调用
+func (h *HttpServer) Use(mdls ...Middleware) {
+	h.mdls = append(h.mdls, mdls...)
+	h.handler = chain(h.serve, h.mdls...)
+}
+
+// WithTrailingSlashPolicy 设置请求路径不规范时的处理方式，默认是 TrailingSlashLenient
+func WithTrailingSlashPolicy(policy TrailingSlashPolicy) HTTPServerOption {
+	return func(server *HttpServer) {
+		server.trailingSlash = policy
+	}
+}
+
+// WithCaseInsensitive 设置静态路径大小写不一致时的处理方式，比如老的客户端请求 /Order/Detail。
+// 总是先按照区分大小写的方式查找，找不到的时候才会忽略大小写再找一次
+func WithCaseInsensitive(policy CaseInsensitivePolicy) HTTPServerOption {
+	return func(server *HttpServer) {
+		server.caseInsensitive = policy
+	}
 }
 
-func NewHTTPServer() *HttpServer {
-	return &HttpServer{
-		router: newRouter(),
+// WithRawPath 使用 URL.EscapedPath() 而不是已经解码的 URL.Path 查找路由，
+// 这样参数里面的 %2F 不会被当成分隔符，比如 /files/:name 可以命中 /files/a%2Fb.txt，
+// 每个参数在命中之后单独解码，name 的值是 a/b.txt。
+// 注意这个时候静态路径也按照转义之后的形式比较，注册的时候需要使用转义之后的写法
+func WithRawPath() HTTPServerOption {
+	return func(server *HttpServer) {
+		server.useRawPath = true
 	}
 }
 
-func (h *HttpServer) Get(path string, handleFunc HandleFunc) {
-	h.addRoute(http.MethodGet, path, handleFunc)
+// WithRouter 替换默认的路由实现
+func WithRouter(r Router) HTTPServerOption {
+	return func(server *HttpServer) {
+		server.router = r
+	}
+}
+
+func (h *HttpServer) addRoute(method string, path string, handleFunc HandleFunc) {
+	if err := h.router.AddRoute(method, path, handleFunc); err != nil {
+		panic(err.Error())
+	}
+}
+
+func (h *HttpServer) Get(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return h.Handle(http.MethodGet, path, handleFunc, mdls...)
+}
+
+func (h *HttpServer) Post(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return h.Handle(http.MethodPost, path, handleFunc, mdls...)
+}
+
+// Handle 注册任意 HTTP 方法的路由，mdls 只作用于这个路由，在 Use 注册的 middleware 里面执行。
+// 使用默认的 Router 时，服务启动之后也可以安全地注册和删除路由
+func (h *HttpServer) Handle(method string, path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	r, err := h.handle(nil, method, path, handleFunc, "", mdls)
+	if err != nil {
+		panic(err.Error())
+	}
+	return r
+}
+
+// TryHandle 和 Handle 一样，但是注册失败的时候返回错误而不是 panic，
+// 适合路由来自配置或者插件，不希望一个错误的路由让整个服务崩溃的场景
+func (h *HttpServer) TryHandle(method string, path string, handleFunc HandleFunc, mdls ...Middleware) (*Route, error) {
+	return h.handle(nil, method, path, handleFunc, "", mdls)
 }
 
-func (h *HttpServer) Post(path string, handleFunc HandleFunc) {
-	h.addRoute(http.MethodPost, path, handleFunc)
+// handle 把路由注册到 hr 或者默认的路由树上，并且记录 handler 的名字，
+// name 为空的时候使用 handleFunc 的函数名
+func (h *HttpServer) handle(hr *hostRoutes, method string, path string, handleFunc HandleFunc, name string, mdls []Middleware) (*Route, error) {
+	r, host := h.router, ""
+	if hr != nil {
+		r, host = hr.router, hr.pattern
+	}
+	if err := r.AddRoute(method, path, chain(handleFunc, mdls...)); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = funcName(handleFunc)
+	}
+	h.mu.Lock()
+	if h.handlerNames == nil {
+		h.handlerNames = make(map[routeKey]string)
+	}
+	h.handlerNames[routeKey{host: host, method: method, pattern: path}] = name
+	h.mu.Unlock()
+	return &Route{server: h, host: hr, method: method, pattern: path}, nil
+}
+
+// RemoveRoute 删除路由，比如下线某个插件或者关闭某个功能开关。
+// 路由不存在或者 Router 不支持删除的时候返回 false。路由的名字也会一起删除
+func (h *HttpServer) RemoveRoute(method string, path string) bool {
+	if !removeRoute(h.router, method, path) {
+		return false
+	}
+	h.mu.Lock()
+	h.removeNames(nil, method, path)
+	h.mu.Unlock()
+	return true
+}
+
+func removeRoute(r Router, method string, path string) bool {
+	rr, ok := r.(RouteRemover)
+	return ok && rr.RemoveRoute(method, path)
 }
 
 // ServeHTTP 处理请求的入口
 func (h *HttpServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	//  框架代码位置
-	ctx := &Context{
-		Req:  request,
-		Resp: writer,
-	}
-	h.serve(ctx)
+	// 注意 handler 返回之后 Context 就会被复用，不能再持有它
+	ctx := h.ctxPool.Get().(*Context)
+	ctx.Req = request
+	ctx.Resp = writer
+	h.requestID(ctx)
+	h.handler(ctx)
+	ctx.reset()
+	h.ctxPool.Put(ctx)
 }
 
 func (h *HttpServer) serve(ctx *Context) {
+	path := ctx.Req.URL.Path
+	if h.useRawPath {
+		path = ctx.Req.URL.EscapedPath()
+	}
+	if cleaned := cleanPath(path); cleaned != path {
+		switch h.trailingSlash {
+		case TrailingSlashStrict:
+			h.notFound(ctx)
+			return
+		case TrailingSlashRedirect:
+			ok, folded := h.route(ctx, cleaned)
+			if !ok {
+				h.notFound(ctx)
+				return
+			}
+			if folded && h.caseInsensitive == CaseInsensitiveRedirect {
+				cleaned = canonicalPath(ctx.match.Pattern, cleaned)
+			}
+			h.redirect(ctx, cleaned)
+			return
+		}
+		path = cleaned
+	}
+
 	// 查找路由，并且执行命中的业务逻辑
-	info, ok := h.findRoute(ctx.Req.Method, ctx.Req.URL.Path)
-	if !ok || info.n.handler == nil {
-		// 路由没有命中，返回404
-		ctx.Resp.WriteHeader(404)
-		ctx.Resp.Write([]byte("NOT FOUND"))
+	ok, folded := h.route(ctx, path)
+	if !ok {
+		h.notFound(ctx)
 		return
 	}
-	ctx.pathParams = info.pathParams
-	info.n.handler(ctx)
+	if folded && h.caseInsensitive == CaseInsensitiveRedirect {
+		if canonical := canonicalPath(ctx.match.Pattern, path); canonical != path {
+			h.redirect(ctx, canonical)
+			return
+		}
+	}
+	if h.useRawPath {
+		unescapeParams(ctx.match.Params)
+	}
+	ctx.match.Handler(ctx)
+}
+
+// route 查找路由，结果放在 ctx.match 里。只有命中了 handler 才返回 true；
+// folded 表示是忽略大小写之后才命中的
+func (h *HttpServer) route(ctx *Context, path string) (ok bool, folded bool) {
+	return h.lookup(ctx.Req.Host, ctx.Req.Method, path, &ctx.match)
+}
+
+// lookup 先区分大小写查找，找不到的时候按照 caseInsensitive 决定要不要忽略大小写再找一次
+func (h *HttpServer) lookup(host string, method string, path string, m *Match) (ok bool, folded bool) {
+	if h.findRoute(host, method, path, m, false) {
+		return true, false
+	}
+	if h.caseInsensitive == CaseSensitive {
+		return false, false
+	}
+	*m = Match{Params: m.Params[:0]}
+	return h.findRoute(host, method, path, m, true), true
+}
+
+func (h *HttpServer) findRoute(host string, method string, path string, m *Match, ignoreCase bool) bool {
+	if hr, params := h.findHost(host, m.Params); hr != nil {
+		m.Params = params
+		if find(hr.router, method, path, m, ignoreCase) && m.Handler != nil {
+			return true
+		}
+		// 回退到默认的路由
+		*m = Match{Params: m.Params[:0]}
+	}
+	return find(h.router, method, path, m, ignoreCase) && m.Handler != nil
+}
+
+func find(r Router, method string, path string, m *Match, ignoreCase bool) bool {
+	if !ignoreCase {
+		return r.FindRoute(method, path, m)
+	}
+	cr, ok := r.(CaseInsensitiveRouter)
+	return ok && cr.FindRouteIgnoreCase(method, path, m)
+}
+
+// unescapeParams 逐个解码参数，解码失败的保持原样
+func unescapeParams(params []Param) {
+	for i, p := range params {
+		if v, err := url.PathUnescape(p.Value); err == nil {
+			params[i].Value = v
+		}
+	}
+}
+
+// canonicalPath 用注册时的路径替换请求路径里面的静态部分，参数部分保持不变
+func canonicalPath(pattern string, path string) string {
+	if pattern == "/" {
+		return pattern
+	}
+	patternSegs := strings.Split(pattern[1:], "/")
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range patternSegs {
+		if i >= len(segs) {
+			break
+		}
+		if seg != "*" && seg[0] != ':' {
+			segs[i] = seg
+		}
+	}
+	return "/" + strings.Join(segs, "/")
+}
+
+func (h *HttpServer) notFound(ctx *Context) {
+	// 路由没有命中，返回404
+	ctx.Resp.WriteHeader(404)
+	ctx.Resp.Write([]byte("NOT FOUND"))
+}
+
+// redirect path 是查找路由时使用的路径，除了 useRawPath 的时候都是解码之后的，
+// 需要重新转义，否则 %3F 之类的字符会改变 URL 的含义
+func (h *HttpServer) redirect(ctx *Context, path string) {
+	code := http.StatusPermanentRedirect
+	if ctx.Req.Method == http.MethodGet || ctx.Req.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	if !h.useRawPath {
+		path = (&url.URL{Path: path}).EscapedPath()
+	}
+	if q := ctx.Req.URL.RawQuery; q != "" {
+		path = path + "?" + q
+	}
+	http.Redirect(ctx.Resp, ctx.Req, path, code)
+}
+
+// cleanPath 和 path.Clean 一样，但是保证以 / 开头
+// 已经规范的路径不会分配内存
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	return path.Clean(p)
 }
 
 func (h *HttpServer) Start(addr string) error {
-	l, err := net.Listen("tcp", addr)
+	l, err := Listen("tcp", addr)
 	if err != nil {
 		return err
 	}
@@ -72,9 +394,99 @@ func (h *HttpServer) Start(addr string) error {
 	// 在这里执行一些业务需要的前置条件
 	// （生命周期回调）
 
-	return http.Serve(l, h)
+	return h.Serve(l)
 }
 
 func (h *HttpServer) Start1(addr string) error {
 	return http.ListenAndServe(addr, h)
 }
+
+// Serve 在多个 listener 上同时提供服务，
+// 比如同时监听两个 TCP 端口和一个 unix socket，或者使用外部传入的 listener（例如 systemd socket activation）。
+// 任意一个 listener 出错都会关闭其余的 listener 并返回该错误；
+// 调用 Shutdown 之后返回 nil，和 http.Server 一样，此时不会等待请求处理完毕
+func (h *HttpServer) Serve(ls ...net.Listener) error {
+	if len(ls) == 0 {
+		return errors.New("web: 至少需要一个 listener")
+	}
+	srv := h.server()
+	h.mu.Lock()
+	h.listeners = append(h.listeners, ls...)
+	h.mu.Unlock()
+	errCh := make(chan error, len(ls))
+	for _, l := range ls {
+		go func(l net.Listener) {
+			errCh <- srv.Serve(l)
+		}(l)
+	}
+	// 如果是 Upgrade 拉起的子进程，通知父进程退出
+	notifyReady()
+
+	var err error
+	for i := 0; i < len(ls); i++ {
+		e := <-errCh
+		if e == nil || errors.Is(e, http.ErrServerClosed) {
+			continue
+		}
+		if err == nil {
+			err = e
+			// 关闭其余的 listener
+			_ = srv.Close()
+		}
+	}
+	return err
+}
+
+// Shutdown 关闭所有 listener，并等待正在处理的请求结束，或者 ctx 过期
+func (h *HttpServer) Shutdown(ctx context.Context) error {
+	return h.server().Shutdown(ctx)
+}
+
+func (h *HttpServer) server() *http.Server {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.srv == nil {
+		h.srv = &http.Server{Handler: h}
+	}
+	return h.srv
+}
+
+// ListenUnix 监听 unix domain socket，并把 socket 文件的权限设置为 perm。
+// 如果 path 上残留了上次进程留下的 socket 文件，会先删除；
+// 如果 path 是一个普通文件，则返回错误，避免误删。
+// 和 Listen 一样，优先使用从父进程继承的 listener
+func ListenUnix(path string, perm os.FileMode) (net.Listener, error) {
+	if l := takeInherited("unix", path); l != nil {
+		return l, nil
+	}
+	fi, err := os.Lstat(path)
+	if err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("web: %s 已存在并且不是 socket 文件", path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(path, perm); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// StartUnix 在 unix domain socket 上提供服务
+func (h *HttpServer) StartUnix(path string, perm os.FileMode) error {
+	l, err := ListenUnix(path, perm)
+	if err != nil {
+		return err
+	}
+	return h.Serve(l)
+}
@@ -1,4 +1,5 @@
-// go:build e2e
+//go:build e2e
+
 package web
 
 import (
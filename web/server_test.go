@@ -1 +1,454 @@
 package web
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpServer_Serve(t *testing.T) {
+	h := NewHTTPServer()
+	h.Get("/user", func(ctx *Context) {
+		_, _ = ctx.Resp.Write([]byte("hello"))
+	})
+
+	tcp1, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	tcp2, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	sock := filepath.Join(t.TempDir(), "web.sock")
+	unix, err := ListenUnix(sock, 0600)
+	require.NoError(t, err)
+
+	fi, err := os.Stat(sock)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.Serve(tcp1, tcp2, unix)
+	}()
+
+	unixClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", sock)
+			},
+		},
+	}
+	testCases := []struct {
+		name   string
+		client *http.Client
+		url    string
+	}{
+		{
+			name:   "tcp 1",
+			client: http.DefaultClient,
+			url:    "http://" + tcp1.Addr().String() + "/user",
+		},
+		{
+			name:   "tcp 2",
+			client: http.DefaultClient,
+			url:    "http://" + tcp2.Addr().String() + "/user",
+		},
+		{
+			name:   "unix",
+			client: unixClient,
+			url:    "http://unix/user",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := tc.client.Get(tc.url)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(body))
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, h.Shutdown(ctx))
+	assert.NoError(t, <-done)
+
+	// 所有 listener 都已经关闭
+	_, err = http.Get("http://" + tcp1.Addr().String() + "/user")
+	assert.Error(t, err)
+	_, err = unixClient.Get("http://unix/user")
+	assert.Error(t, err)
+}
+
+func TestHttpServer_Serve_NoListener(t *testing.T) {
+	h := NewHTTPServer()
+	assert.EqualError(t, h.Serve(), "web: 至少需要一个 listener")
+}
+
+func TestListenUnix(t *testing.T) {
+	dir := t.TempDir()
+
+	// 普通文件不允许覆盖
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, []byte("abc"), 0600))
+	_, err := ListenUnix(file, 0600)
+	assert.Error(t, err)
+
+	// 残留的 socket 文件会被清理掉
+	sock := filepath.Join(dir, "web.sock")
+	l, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, l.Close())
+	_, err = os.Lstat(sock)
+	require.NoError(t, err)
+
+	l, err = ListenUnix(sock, 0660)
+	require.NoError(t, err)
+	defer l.Close()
+	fi, err := os.Stat(sock)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), fi.Mode().Perm())
+}
+
+func TestHttpServer_ServeHTTP(t *testing.T) {
+	h := NewHTTPServer()
+	h.Get("/user/:id", func(ctx *Context) {
+		id, _ := ctx.PathValue("id")
+		_, _ = ctx.Resp.Write([]byte(id))
+	})
+	h.Get("/order/:id([0-9]+)", func(ctx *Context) {
+		id, _ := ctx.PathValue("id")
+		_, ok := ctx.PathValue("name")
+		_, _ = ctx.Resp.Write([]byte(fmt.Sprintf("%s %v", id, ok)))
+	})
+
+	testCases := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "param",
+			path:     "/user/123",
+			wantCode: http.StatusOK,
+			wantBody: "123",
+		},
+		{
+			// 复用的 Context 不能带着上一个请求的参数
+			name:     "reg",
+			path:     "/order/456",
+			wantCode: http.StatusOK,
+			wantBody: "456 false",
+		},
+		{
+			name:     "not found",
+			path:     "/order/abc",
+			wantCode: http.StatusNotFound,
+			wantBody: "NOT FOUND",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+// discardWriter 每次请求都复用，避免 httptest.ResponseRecorder 的内存分配干扰结果
+type discardWriter struct {
+	header http.Header
+}
+
+func (d *discardWriter) Header() http.Header {
+	return d.header
+}
+
+func (d *discardWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (d *discardWriter) WriteHeader(statusCode int) {}
+
+func BenchmarkHttpServer_ServeHTTP(b *testing.B) {
+	h := NewHTTPServer()
+	var handler HandleFunc = func(ctx *Context) {}
+	h.Get("/user/home", handler)
+	h.Get("/order/:id/detail", handler)
+	h.Get("/reg/:id([0-9]+)", handler)
+	h.Get("/star/*", handler)
+
+	benchmarks := []struct {
+		name string
+		path string
+	}{
+		{name: "static", path: "/user/home"},
+		{name: "param", path: "/order/123/detail"},
+		{name: "regex", path: "/reg/123"},
+		{name: "wildcard", path: "/star/a/b/c"},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, bm.path, nil)
+			w := &discardWriter{header: http.Header{}}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.ServeHTTP(w, req)
+			}
+		})
+	}
+}
+
+func TestWithRouter(t *testing.T) {
+	r := NewRadixRouter()
+	h := NewHTTPServer(WithRouter(r))
+	h.Get("/user/:id", func(ctx *Context) {
+		id, _ := ctx.PathValue("id")
+		_, _ = ctx.Resp.Write([]byte(id))
+	})
+	assert.Len(t, r.Routes(), 1)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user/123", nil))
+	assert.Equal(t, "123", recorder.Body.String())
+}
+
+func TestWithTrailingSlashPolicy(t *testing.T) {
+	testCases := []struct {
+		name         string
+		policy       TrailingSlashPolicy
+		method       string
+		target       string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+	}{
+		{name: "lenient", policy: TrailingSlashLenient, target: "/login", wantCode: http.StatusOK, wantBody: "login"},
+		{name: "lenient trailing", policy: TrailingSlashLenient, target: "/login/", wantCode: http.StatusOK, wantBody: "login"},
+		{name: "lenient double", policy: TrailingSlashLenient, target: "//login", wantCode: http.StatusOK, wantBody: "login"},
+		{name: "lenient dot", policy: TrailingSlashLenient, target: "/user/./../login", wantCode: http.StatusOK, wantBody: "login"},
+		{name: "strict", policy: TrailingSlashStrict, target: "/login", wantCode: http.StatusOK, wantBody: "login"},
+		{name: "strict trailing", policy: TrailingSlashStrict, target: "/login/", wantCode: http.StatusNotFound},
+		{name: "strict double", policy: TrailingSlashStrict, target: "/user//login", wantCode: http.StatusNotFound},
+		{name: "redirect", policy: TrailingSlashRedirect, target: "/login", wantCode: http.StatusOK, wantBody: "login"},
+		{
+			name: "redirect get", policy: TrailingSlashRedirect, target: "/login/?a=b",
+			wantCode: http.StatusMovedPermanently, wantLocation: "/login?a=b",
+		},
+		{
+			name: "redirect post", policy: TrailingSlashRedirect, method: http.MethodPost, target: "//login",
+			wantCode: http.StatusPermanentRedirect, wantLocation: "/login",
+		},
+		{name: "redirect not found", policy: TrailingSlashRedirect, target: "/abc/", wantCode: http.StatusNotFound},
+		// Location 里面的路径要重新转义，不能把 %3F 变成查询参数
+		{
+			name: "redirect escaped", policy: TrailingSlashRedirect, target: "/files//a%3Fb",
+			wantCode: http.StatusMovedPermanently, wantLocation: "/files/a%3Fb",
+		},
+		{
+			name: "redirect space", policy: TrailingSlashRedirect, target: "/files/a%20b/?c=d",
+			wantCode: http.StatusMovedPermanently, wantLocation: "/files/a%20b?c=d",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHTTPServer(WithTrailingSlashPolicy(tc.policy))
+			h.Get("/login", func(ctx *Context) {
+				_, _ = ctx.Resp.Write([]byte("login"))
+			})
+			h.Post("/login", func(ctx *Context) {
+				_, _ = ctx.Resp.Write([]byte("login"))
+			})
+			h.Get("/files/:name", func(ctx *Context) {})
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			// //login 这种路径不能交给 url.Parse，它会被当成域名
+			u, err := url.ParseRequestURI(tc.target)
+			require.NoError(t, err)
+			req.URL = u
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, recorder.Body.String())
+			}
+			assert.Equal(t, tc.wantLocation, recorder.Header().Get("Location"))
+		})
+	}
+}
+
+func TestWithCaseInsensitive(t *testing.T) {
+	testCases := []struct {
+		name         string
+		policy       CaseInsensitivePolicy
+		router       Router
+		target       string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+	}{
+		{name: "sensitive", policy: CaseSensitive, target: "/Order/Detail/AbC", wantCode: http.StatusNotFound},
+		{name: "exact", policy: CaseInsensitiveServe, target: "/order/detail/AbC", wantCode: http.StatusOK, wantBody: "AbC"},
+		// 参数的值保持原样
+		{name: "serve", policy: CaseInsensitiveServe, target: "/Order/DETAIL/AbC", wantCode: http.StatusOK, wantBody: "AbC"},
+		{
+			name: "redirect", policy: CaseInsensitiveRedirect, target: "/Order/DETAIL/AbC?a=B",
+			wantCode: http.StatusMovedPermanently, wantLocation: "/order/detail/AbC?a=B",
+		},
+		{
+			name: "redirect with trailing slash", policy: CaseInsensitiveRedirect, target: "/Order/DETAIL/AbC/",
+			wantCode: http.StatusMovedPermanently, wantLocation: "/order/detail/AbC",
+		},
+		{
+			name: "wildcard", policy: CaseInsensitiveRedirect, target: "/STATIC/JS/A.js",
+			wantCode: http.StatusMovedPermanently, wantLocation: "/static/JS/A.js",
+		},
+		{
+			name: "redirect escaped", policy: CaseInsensitiveRedirect, target: "/ORDER/detail/a%3Fb%20c",
+			wantCode: http.StatusMovedPermanently, wantLocation: "/order/detail/a%3Fb%20c",
+		},
+		{
+			name: "router not supported", policy: CaseInsensitiveServe, router: NewRadixRouter(),
+			target: "/Order/DETAIL/AbC", wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := []HTTPServerOption{WithCaseInsensitive(tc.policy), WithTrailingSlashPolicy(TrailingSlashRedirect)}
+			if tc.router != nil {
+				opts = append(opts, WithRouter(tc.router))
+			}
+			h := NewHTTPServer(opts...)
+			h.Get("/order/detail/:id", func(ctx *Context) {
+				id, _ := ctx.PathValue("id")
+				_, _ = ctx.Resp.Write([]byte(id))
+			})
+			h.Get("/static/*", func(ctx *Context) {})
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, recorder.Body.String())
+			}
+			assert.Equal(t, tc.wantLocation, recorder.Header().Get("Location"))
+		})
+	}
+}
+
+func TestWithRawPath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []HTTPServerOption
+		target   string
+		wantCode int
+		wantBody string
+	}{
+		{name: "decoded path", target: "/files/a%2Fb.txt", wantCode: http.StatusNotFound},
+		{name: "raw path", opts: []HTTPServerOption{WithRawPath()}, target: "/files/a%2Fb.txt", wantCode: http.StatusOK, wantBody: "a/b.txt"},
+		{name: "raw path space", opts: []HTTPServerOption{WithRawPath()}, target: "/files/a%20b.txt", wantCode: http.StatusOK, wantBody: "a b.txt"},
+		{name: "no escape", opts: []HTTPServerOption{WithRawPath()}, target: "/files/ab.txt", wantCode: http.StatusOK, wantBody: "ab.txt"},
+		{name: "static", opts: []HTTPServerOption{WithRawPath()}, target: "/static/a%20b", wantCode: http.StatusOK, wantBody: "static"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHTTPServer(tc.opts...)
+			h.Get("/files/:name", func(ctx *Context) {
+				name, _ := ctx.PathValue("name")
+				_, _ = ctx.Resp.Write([]byte(name))
+			})
+			h.Get("/static/a%20b", func(ctx *Context) {
+				_, _ = ctx.Resp.Write([]byte("static"))
+			})
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+// TestHttpServer_RuntimeRoutes 在处理请求的同时注册和删除路由，需要配合 -race 运行
+func TestHttpServer_RuntimeRoutes(t *testing.T) {
+	h := NewHTTPServer()
+	h.Get("/user/:id", func(ctx *Context) {
+		_, _ = ctx.Resp.Write([]byte("user"))
+	})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				recorder := httptest.NewRecorder()
+				h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user/123", nil))
+				assert.Equal(t, "user", recorder.Body.String())
+				recorder = httptest.NewRecorder()
+				h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/plugin/abc", nil))
+				assert.Contains(t, []int{http.StatusOK, http.StatusNotFound}, recorder.Code)
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		path := fmt.Sprintf("/plugin/feature%d", i)
+		h.Get(path, func(ctx *Context) {})
+		h.Get("/plugin/:name", func(ctx *Context) {})
+		h.Host(fmt.Sprintf("t%d.example.com", i%10)).Get(path, func(ctx *Context) {})
+		assert.True(t, h.RemoveRoute(http.MethodGet, "/plugin/:name"))
+	}
+	close(stop)
+	wg.Wait()
+
+	assert.Len(t, h.Routes(), 1+200+200)
+}
+
+func TestHttpServer_TryHandle(t *testing.T) {
+	h := NewHTTPServer()
+	_, err := h.TryHandle(http.MethodGet, "/user/:id", func(ctx *Context) {})
+	require.NoError(t, err)
+
+	_, err = h.TryHandle(http.MethodGet, "/user/:name", func(ctx *Context) {})
+	var conflict *ErrConflict
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, ":id", conflict.Existing)
+	assert.Equal(t, ":name", conflict.New)
+
+	_, err = h.Host("api.example.com").TryHandle(http.MethodGet, "user", func(ctx *Context) {})
+	assert.ErrorIs(t, err, ErrPathNoLeadingSlash)
+
+	// Handle 仍然 panic
+	assert.PanicsWithValue(t, "web: 路径冲突，重复注册[/user/:id]", func() {
+		h.Get("/user/:id", func(ctx *Context) {})
+	})
+}
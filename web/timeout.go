@@ -0,0 +1,148 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeoutOption Timeout 的选项
+type TimeoutOption func(t *timeout)
+
+// TimeoutStatus 超时的时候返回的状态码，默认是 503，
+// 如果服务本身是一个网关，可以用 504
+func TimeoutStatus(code int) TimeoutOption {
+	return func(t *timeout) {
+		t.code = code
+	}
+}
+
+// TimeoutBody 超时的时候返回的响应，默认是大写的状态码描述，比如 SERVICE UNAVAILABLE
+func TimeoutBody(body string) TimeoutOption {
+	return func(t *timeout) {
+		t.body = []byte(body)
+	}
+}
+
+type timeout struct {
+	d    time.Duration
+	code int
+	body []byte
+}
+
+// Timeout 给请求加上超时，可以用 Use 作用于所有的路由，也可以只作用于某个路由：
+//
+//	h.Get("/report", fn, web.Timeout(time.Second, web.TimeoutStatus(http.StatusGatewayTimeout)))
+//
+// Req.Context() 会带上截止时间，超时之后被取消。
+// 和 http.TimeoutHandler 一样，业务逻辑在另外的 goroutine 里面执行，响应先写到缓冲区，
+// 在超时之前完成才会真正写出去；超时之后返回 TimeoutStatus 和 TimeoutBody，
+// 业务逻辑之后再写响应会得到 http.ErrHandlerTimeout。
+// 业务逻辑使用的是一个复制出来的 Context，正常完成的时候 Set 保存的值和路由查找的结果会复制回来，超时的时候则丢弃
+func Timeout(d time.Duration, opts ...TimeoutOption) Middleware {
+	t := &timeout{d: d, code: http.StatusServiceUnavailable}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.body == nil {
+		t.body = []byte(strings.ToUpper(http.StatusText(t.code)))
+	}
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			t.serve(ctx, next)
+		}
+	}
+}
+
+func (t *timeout) serve(ctx *Context, next HandleFunc) {
+	c, cancel := context.WithTimeout(ctx.Req.Context(), t.d)
+	defer cancel()
+
+	tw := &timeoutWriter{w: ctx.Resp, h: make(http.Header)}
+	// 超时之后当前的 Context 会被放回池子里复用，所以业务逻辑只能用一份副本
+	sub := ctx.clone(ctx.Req.WithContext(c), tw)
+
+	done := make(chan struct{})
+	panicChan := make(chan any, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicChan <- p
+			}
+		}()
+		next(sub)
+		close(done)
+	}()
+
+	select {
+	case p := <-panicChan:
+		panic(p)
+	case <-done:
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		dst := ctx.Resp.Header()
+		for k, vv := range tw.h {
+			dst[k] = vv
+		}
+		if !tw.wroteHeader {
+			tw.code = http.StatusOK
+		}
+		ctx.Resp.WriteHeader(tw.code)
+		_, _ = ctx.Resp.Write(tw.buf.Bytes())
+		ctx.merge(sub)
+	case <-c.Done():
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		if c.Err() == context.DeadlineExceeded {
+			ctx.Resp.WriteHeader(t.code)
+			_, _ = ctx.Resp.Write(t.body)
+		}
+		// 客户端断开的时候就不用写响应了
+		tw.timedOut = true
+	}
+}
+
+// timeoutWriter 缓存业务逻辑写的响应，超时之后拒绝继续写
+type timeoutWriter struct {
+	w http.ResponseWriter
+	h http.Header
+
+	mu          sync.Mutex
+	buf         bytes.Buffer
+	timedOut    bool
+	wroteHeader bool
+	code        int
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.code = code
+}
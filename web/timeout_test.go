@@ -0,0 +1,69 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	lateErr := make(chan error, 1)
+	h := NewHTTPServer()
+	h.Use(Timeout(time.Second))
+	h.Use(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			ctx.Set("user", "why")
+			next(ctx)
+		}
+	})
+	h.Get("/fast/:id", func(ctx *Context) {
+		_, ok := ctx.Req.Context().Deadline()
+		assert.True(t, ok)
+		id, _ := ctx.PathValue("id")
+		ctx.Resp.Header().Set("X-Id", id)
+		ctx.Resp.WriteHeader(http.StatusCreated)
+		_, _ = ctx.Resp.Write([]byte("fast " + MustGetAs[string](ctx, "user")))
+	})
+	h.Get("/slow", func(ctx *Context) {
+		<-ctx.Req.Context().Done()
+		// 等外面写完超时的响应
+		time.Sleep(10 * time.Millisecond)
+		_, err := ctx.Resp.Write([]byte("slow"))
+		lateErr <- err
+	}, Timeout(10*time.Millisecond))
+	h.Get("/gateway", func(ctx *Context) {
+		<-ctx.Context().Done()
+	}, Timeout(10*time.Millisecond, TimeoutStatus(http.StatusGatewayTimeout), TimeoutBody("timeout")))
+	h.Get("/panic", func(ctx *Context) {
+		panic("boom")
+	})
+
+	testCases := []struct {
+		name       string
+		path       string
+		wantCode   int
+		wantBody   string
+		wantHeader string
+	}{
+		{name: "fast", path: "/fast/123", wantCode: http.StatusCreated, wantBody: "fast why", wantHeader: "123"},
+		{name: "slow", path: "/slow", wantCode: http.StatusServiceUnavailable, wantBody: "SERVICE UNAVAILABLE"},
+		{name: "gateway", path: "/gateway", wantCode: http.StatusGatewayTimeout, wantBody: "timeout"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantHeader, recorder.Header().Get("X-Id"))
+		})
+	}
+	assert.Equal(t, http.ErrHandlerTimeout, <-lateErr)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	})
+}
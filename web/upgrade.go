@@ -0,0 +1,229 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 平滑重启（graceful re-exec）：
+// 父进程把正在监听的 socket 通过 ExtraFiles 传给子进程，fd 从 3 开始依次排列，
+// 个数放在 envListenFDs 里；最后再传一个管道的写端，fd 放在 envReadyFD 里。
+// 子进程通过 Listen 拿到继承的 listener，开始 Serve 之后往管道里写一个字节，
+// 父进程收到之后再 Shutdown，这样整个过程中 socket 一直处于监听状态，不会丢连接
+const (
+	envListenFDs = "WEB_LISTEN_FDS"
+	envReadyFD   = "WEB_READY_FD"
+
+	// 0，1，2 是标准输入输出
+	inheritedFDStart = 3
+)
+
+var (
+	inheritOnce sync.Once
+	inheritMu   sync.Mutex
+	// 从父进程继承、还没有被 Listen 取走的 listener
+	inherited []net.Listener
+	// 还没有通知父进程的话，这里是管道的写端
+	readyFile *os.File
+)
+
+// upgradeCommand 返回拉起子进程的命令，默认是用同样的参数重新执行当前程序
+var upgradeCommand = func() (string, []string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", nil, err
+	}
+	return path, os.Args[1:], nil
+}
+
+func loadInherited() {
+	inheritOnce.Do(func() {
+		n, err := strconv.Atoi(os.Getenv(envListenFDs))
+		if err != nil {
+			return
+		}
+		for i := 0; i < n; i++ {
+			f := os.NewFile(uintptr(inheritedFDStart+i), "inherited-listener-"+strconv.Itoa(i))
+			l, err := net.FileListener(f)
+			// FileListener 会 dup 一份 fd，原来的可以关掉了
+			_ = f.Close()
+			if err != nil {
+				continue
+			}
+			inherited = append(inherited, l)
+		}
+		if fd, err := strconv.Atoi(os.Getenv(envReadyFD)); err == nil {
+			readyFile = os.NewFile(uintptr(fd), "upgrade-ready")
+		}
+		// 避免再往下传给孙子进程
+		_ = os.Unsetenv(envListenFDs)
+		_ = os.Unsetenv(envReadyFD)
+	})
+}
+
+// Listen 与 net.Listen 相同，
+// 但如果当前进程是 Upgrade 拉起的子进程，会优先使用从父进程继承的、地址相同的 listener
+func Listen(network, addr string) (net.Listener, error) {
+	if l := takeInherited(network, addr); l != nil {
+		return l, nil
+	}
+	return net.Listen(network, addr)
+}
+
+func takeInherited(network, addr string) net.Listener {
+	loadInherited()
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+	for i, l := range inherited {
+		if addrMatch(network, addr, l.Addr()) {
+			inherited = append(inherited[:i], inherited[i+1:]...)
+			return l
+		}
+	}
+	return nil
+}
+
+// addrMatch 判断 net.Listen(network, addr) 得到的地址是不是 got
+// 例如 ":8081" 和 "[::]:8081" 是同一个地址
+func addrMatch(network, addr string, got net.Addr) bool {
+	switch got := got.(type) {
+	case *net.TCPAddr:
+		if !strings.HasPrefix(network, "tcp") {
+			return false
+		}
+		want, err := net.ResolveTCPAddr(network, addr)
+		if err != nil || want.Port != got.Port {
+			return false
+		}
+		if want.IP == nil || want.IP.IsUnspecified() {
+			return got.IP == nil || got.IP.IsUnspecified()
+		}
+		return want.IP.Equal(got.IP)
+	case *net.UnixAddr:
+		return network == got.Network() && addr == got.Name
+	}
+	return false
+}
+
+// notifyReady 在所有继承的 listener 都被取走并开始 Serve 之后，通知父进程可以退出了
+func notifyReady() {
+	loadInherited()
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+	if readyFile == nil || len(inherited) > 0 {
+		return
+	}
+	_, _ = readyFile.Write([]byte{1})
+	_ = readyFile.Close()
+	readyFile = nil
+}
+
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Upgrade 平滑重启：拉起一个新的进程，把当前所有的 listener 交给它，
+// 等新进程开始提供服务之后，当前进程调用 Shutdown 处理完剩下的请求。
+// 新进程需要用同样的地址调用 Start/StartUnix，或者自己调用 Listen 拿到 listener 再 Serve。
+// 如果 ctx 过期之前新进程没有准备好，新进程会被杀掉，当前进程继续提供服务。
+// 一般在收到 SIGHUP 之类的信号时调用，Upgrade 返回之后 Start 也会返回，进程就可以退出了
+func (h *HttpServer) Upgrade(ctx context.Context) error {
+	h.mu.Lock()
+	ls := make([]net.Listener, len(h.listeners))
+	copy(ls, h.listeners)
+	h.mu.Unlock()
+	if len(ls) == 0 {
+		return errors.New("web: 没有可以交给新进程的 listener")
+	}
+
+	files := make([]*os.File, 0, len(ls)+1)
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, l := range ls {
+		fl, ok := l.(filer)
+		if !ok {
+			return fmt.Errorf("web: 不支持传递 %T 类型的 listener", l)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	files = append(files, w)
+
+	path, args, err := upgradeCommand()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(upgradeEnv(os.Environ()),
+		envListenFDs+"="+strconv.Itoa(len(ls)),
+		envReadyFD+"="+strconv.Itoa(inheritedFDStart+len(ls)))
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	// 关掉父进程这边的写端，子进程退出的时候 Read 才会返回
+	_ = w.Close()
+	files = files[:len(files)-1]
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := r.Read(buf)
+		ready <- err
+	}()
+	select {
+	case err = <-ready:
+		if err != nil {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+			return fmt.Errorf("web: 新进程没有准备好: %w", err)
+		}
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return ctx.Err()
+	}
+	// 子进程不归我们管了
+	_ = cmd.Process.Release()
+
+	for _, l := range ls {
+		// unix socket 关闭的时候默认会删除文件，而新进程还在用
+		if ul, ok := l.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	return h.Shutdown(ctx)
+}
+
+// upgradeEnv 去掉当前进程自己继承来的环境变量
+func upgradeEnv(environ []string) []string {
+	res := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		if strings.HasPrefix(kv, envListenFDs+"=") || strings.HasPrefix(kv, envReadyFD+"=") {
+			continue
+		}
+		res = append(res, kv)
+	}
+	return res
+}
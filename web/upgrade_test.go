@@ -0,0 +1,162 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const envTestUpgradeAddr = "WEB_TEST_UPGRADE_ADDR"
+
+func TestHttpServer_Upgrade(t *testing.T) {
+	old := upgradeCommand
+	defer func() {
+		upgradeCommand = old
+	}()
+	upgradeCommand = func() (string, []string, error) {
+		return os.Args[0], []string{"-test.run=^TestHttpServer_UpgradeChild$"}, nil
+	}
+
+	h := NewHTTPServer()
+	h.Get("/who", func(ctx *Context) {
+		_, _ = ctx.Resp.Write([]byte("parent"))
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	t.Setenv(envTestUpgradeAddr, addr)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.Serve(l)
+	}()
+	assert.Equal(t, "parent", get(t, "http://"+addr+"/who"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, h.Upgrade(ctx))
+	assert.NoError(t, <-done)
+
+	// 父进程已经退出服务，同一个端口现在由子进程处理
+	assert.Equal(t, "child", get(t, "http://"+addr+"/who"))
+	assert.Equal(t, "bye", get(t, "http://"+addr+"/exit"))
+}
+
+// TestHttpServer_UpgradeChild 是 TestHttpServer_Upgrade 拉起的子进程
+func TestHttpServer_UpgradeChild(t *testing.T) {
+	addr := os.Getenv(envTestUpgradeAddr)
+	if addr == "" || os.Getenv(envListenFDs) == "" {
+		t.Skip("只在 TestHttpServer_Upgrade 拉起的子进程里运行")
+	}
+	h := NewHTTPServer()
+	h.Get("/who", func(ctx *Context) {
+		_, _ = ctx.Resp.Write([]byte("child"))
+	})
+	h.Get("/exit", func(ctx *Context) {
+		_, _ = ctx.Resp.Write([]byte("bye"))
+		go func() {
+			_ = h.Shutdown(context.Background())
+		}()
+	})
+	assert.NoError(t, h.Start(addr))
+}
+
+func TestHttpServer_Upgrade_NoListener(t *testing.T) {
+	h := NewHTTPServer()
+	assert.EqualError(t, h.Upgrade(context.Background()), "web: 没有可以交给新进程的 listener")
+}
+
+func TestListen_Inherited(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	loadInherited()
+	inheritMu.Lock()
+	inherited = append(inherited, l)
+	inheritMu.Unlock()
+
+	got, err := Listen("tcp", l.Addr().String())
+	require.NoError(t, err)
+	assert.Same(t, l, got)
+
+	// 已经被取走了，再 Listen 就会因为端口被占用而失败
+	_, err = Listen("tcp", l.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestAddrMatch(t *testing.T) {
+	testCases := []struct {
+		name    string
+		network string
+		addr    string
+		got     net.Addr
+		want    bool
+	}{
+		{
+			name:    "unspecified",
+			network: "tcp",
+			addr:    ":8081",
+			got:     &net.TCPAddr{IP: net.IPv6unspecified, Port: 8081},
+			want:    true,
+		},
+		{
+			name:    "ip",
+			network: "tcp",
+			addr:    "127.0.0.1:8081",
+			got:     &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8081},
+			want:    true,
+		},
+		{
+			name:    "different ip",
+			network: "tcp",
+			addr:    "127.0.0.1:8081",
+			got:     &net.TCPAddr{IP: net.IPv6unspecified, Port: 8081},
+		},
+		{
+			name:    "different port",
+			network: "tcp",
+			addr:    ":8082",
+			got:     &net.TCPAddr{IP: net.IPv6unspecified, Port: 8081},
+		},
+		{
+			name:    "unix",
+			network: "unix",
+			addr:    "/tmp/web.sock",
+			got:     &net.UnixAddr{Net: "unix", Name: "/tmp/web.sock"},
+			want:    true,
+		},
+		{
+			name:    "network mismatch",
+			network: "unix",
+			addr:    ":8081",
+			got:     &net.TCPAddr{IP: net.IPv6unspecified, Port: 8081},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, addrMatch(tc.network, tc.addr, tc.got))
+		})
+	}
+}
+
+func TestUpgradeEnv(t *testing.T) {
+	env := upgradeEnv([]string{"PATH=/bin", envListenFDs + "=2", envReadyFD + "=5", "HOME=/root"})
+	assert.Equal(t, []string{"PATH=/bin", "HOME=/root"}, env)
+}
+
+func get(t *testing.T, url string) string {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
@@ -0,0 +1,166 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Route 注册路由之后返回，用来补充这个路由的信息，比如：
+//
+//	h.Get("/param/:id/detail", fn).Name("param.detail")
+type Route struct {
+	server *HttpServer
+	// 通过 HostGroup 注册的路由才有
+	host    *hostRoutes
+	method  string
+	pattern string
+}
+
+// namedRoute 有名字的路由，注册名字的时候就切分好路径，生成 URL 的时候不需要再解析
+type namedRoute struct {
+	host    *hostRoutes
+	method  string
+	pattern string
+	segs    []urlSeg
+}
+
+// urlSeg 路径里的一段，key 为空表示静态的一段
+type urlSeg struct {
+	static string
+	key    string
+	expr   *regexp.Regexp
+}
+
+// Name 给路由起一个名字，之后可以用 HttpServer.URL 反向生成 URL。名字不能重复
+func (r *Route) Name(name string) *Route {
+	if err := r.setName(name); err != nil {
+		panic(err.Error())
+	}
+	return r
+}
+
+func (r *Route) setName(name string) error {
+	h := r.server
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.names == nil {
+		h.names = make(map[string]*namedRoute)
+	}
+	if nr, ok := h.names[name]; ok {
+		return fmt.Errorf("web: 路由名字冲突，[%s] 已经被 [%s] 使用", name, nr.pattern)
+	}
+	nr := &namedRoute{host: r.host, method: r.method, pattern: r.pattern}
+	if r.pattern != "/" {
+		for _, seg := range strings.Split(r.pattern[1:], "/") {
+			if regs := regPathPattern.FindStringSubmatch(seg); regs != nil {
+				// 注册的时候已经校验过了
+				nr.segs = append(nr.segs, urlSeg{key: regs[1], expr: regexp.MustCompile(regs[2])})
+			} else if seg == "*" || seg[0] == ':' {
+				nr.segs = append(nr.segs, urlSeg{key: strings.TrimPrefix(seg, ":")})
+			} else {
+				nr.segs = append(nr.segs, urlSeg{static: seg})
+			}
+		}
+	}
+	h.names[name] = nr
+	return nil
+}
+
+// removeNames 删除路由之后，指向这个路由的名字和记录的 handler 的名字也不能再用了。调用方需要持有 h.mu
+func (h *HttpServer) removeNames(host *hostRoutes, method string, pattern string) {
+	key := routeKey{method: method, pattern: pattern}
+	if host != nil {
+		key.host = host.pattern
+	}
+	delete(h.handlerNames, key)
+	for name, nr := range h.names {
+		if nr.host == host && nr.method == method && nr.pattern == pattern {
+			delete(h.names, name)
+		}
+	}
+}
+
+// URL 根据路由的名字生成路径，params 是成对的参数名和参数值，比如：
+//
+//	h.URL("param.detail", "id", "123") // /param/123/detail
+//
+// 正则路径的参数值必须能够通过校验，参数值会被转义；
+// 通配符的参数名是 *，它的值可以包含 /。
+// 通过 HostGroup 注册的路由会带上域名，比如 //api.example.com/user/123，
+// 域名里的参数和路径参数一样通过 params 传入。
+// 缺少参数或者多了路由里面没有的参数都会返回错误
+func (h *HttpServer) URL(name string, params ...string) (string, error) {
+	h.mu.Lock()
+	nr, ok := h.names[name]
+	h.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("web: 找不到名字为 [%s] 的路由", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("web: 路由 [%s] 的参数必须是成对的参数名和参数值", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	var sb strings.Builder
+	if nr.host != nil {
+		sb.WriteString("//")
+		for i, label := range nr.host.labels {
+			if i > 0 {
+				sb.WriteByte('.')
+			}
+			if label[0] != ':' {
+				sb.WriteString(label)
+				continue
+			}
+			key := label[1:]
+			val, ok := values[key]
+			if !ok {
+				return "", fmt.Errorf("web: 路由 [%s] 缺少参数 %s", name, key)
+			}
+			delete(values, key)
+			// 域名参数只能匹配一段
+			if val == "" || strings.ContainsAny(val, "./:@?#") {
+				return "", fmt.Errorf("web: 路由 [%s] 的参数 %s 的值 %s 不是合法的域名", name, key, val)
+			}
+			sb.WriteString(val)
+		}
+	}
+	if len(nr.segs) == 0 {
+		sb.WriteByte('/')
+	}
+	for _, seg := range nr.segs {
+		sb.WriteByte('/')
+		if seg.key == "" {
+			sb.WriteString(seg.static)
+			continue
+		}
+
+		val, ok := values[seg.key]
+		if !ok {
+			return "", fmt.Errorf("web: 路由 [%s] 缺少参数 %s", name, seg.key)
+		}
+		delete(values, seg.key)
+		if seg.expr != nil && !seg.expr.MatchString(val) {
+			return "", fmt.Errorf("web: 路由 [%s] 的参数 %s 的值 %s 不符合 %s", name, seg.key, val, seg.expr)
+		}
+		if seg.key == "*" {
+			// 通配符可以匹配多段，每一段单独转义
+			subs := strings.Split(val, "/")
+			for i, sub := range subs {
+				subs[i] = url.PathEscape(sub)
+			}
+			sb.WriteString(strings.Join(subs, "/"))
+			continue
+		}
+		sb.WriteString(url.PathEscape(val))
+	}
+	for key := range values {
+		return "", fmt.Errorf("web: 路由 [%s] 没有参数 %s", name, key)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,122 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpServer_URL(t *testing.T) {
+	h := NewHTTPServer()
+	var mockHandler HandleFunc = func(ctx *Context) {}
+	h.Get("/", mockHandler).Name("index")
+	h.Get("/param/:id/detail", mockHandler).Name("param.detail")
+	h.Get("/order/:id([0-9]+)", mockHandler).Name("order")
+	h.Post("/static/*", mockHandler).Name("static")
+
+	assert.PanicsWithValue(t, "web: 路由名字冲突，[order] 已经被 [/order/:id([0-9]+)] 使用", func() {
+		h.Get("/order/:id([0-9]+)/detail", mockHandler).Name("order")
+	})
+
+	testCases := []struct {
+		name    string
+		route   string
+		params  []string
+		wantURL string
+		wantErr string
+	}{
+		{name: "root", route: "index", wantURL: "/"},
+		{name: "param", route: "param.detail", params: []string{"id", "123"}, wantURL: "/param/123/detail"},
+		{name: "escape", route: "param.detail", params: []string{"id", "a b/c"}, wantURL: "/param/a%20b%2Fc/detail"},
+		{name: "reg", route: "order", params: []string{"id", "123"}, wantURL: "/order/123"},
+		{
+			name: "reg mismatch", route: "order", params: []string{"id", "abc"},
+			wantErr: "web: 路由 [order] 的参数 id 的值 abc 不符合 [0-9]+",
+		},
+		{name: "star", route: "static", params: []string{"*", "js/a b.js"}, wantURL: "/static/js/a%20b.js"},
+		{name: "not found", route: "user", wantErr: "web: 找不到名字为 [user] 的路由"},
+		{name: "missing param", route: "param.detail", wantErr: "web: 路由 [param.detail] 缺少参数 id"},
+		{
+			name: "unknown param", route: "param.detail", params: []string{"id", "1", "name", "abc"},
+			wantErr: "web: 路由 [param.detail] 没有参数 name",
+		},
+		{
+			name: "odd params", route: "param.detail", params: []string{"id"},
+			wantErr: "web: 路由 [param.detail] 的参数必须是成对的参数名和参数值",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := h.URL(tc.route, tc.params...)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantURL, u)
+		})
+	}
+}
+
+func TestHttpServer_URL_Host(t *testing.T) {
+	h := NewHTTPServer()
+	var mockHandler HandleFunc = func(ctx *Context) {}
+	h.Host("API.example.com").Get("/user/:id", mockHandler).Name("api.user")
+	h.Host(":tenant.example.com").Get("/", mockHandler).Name("tenant.index")
+
+	testCases := []struct {
+		name    string
+		route   string
+		params  []string
+		wantURL string
+		wantErr string
+	}{
+		{name: "host", route: "api.user", params: []string{"id", "123"}, wantURL: "//api.example.com/user/123"},
+		{name: "host param", route: "tenant.index", params: []string{"tenant", "acme"}, wantURL: "//acme.example.com/"},
+		{name: "missing host param", route: "tenant.index", wantErr: "web: 路由 [tenant.index] 缺少参数 tenant"},
+		{
+			name: "invalid host param", route: "tenant.index", params: []string{"tenant", "a.b"},
+			wantErr: "web: 路由 [tenant.index] 的参数 tenant 的值 a.b 不是合法的域名",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := h.URL(tc.route, tc.params...)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantURL, u)
+		})
+	}
+}
+
+func TestHttpServer_URL_RemoveRoute(t *testing.T) {
+	h := NewHTTPServer()
+	var mockHandler HandleFunc = func(ctx *Context) {}
+	h.Get("/user/:id", mockHandler).Name("user")
+	h.Post("/user/:id", mockHandler).Name("user.update")
+	api := h.Host("api.example.com")
+	api.Get("/user/:id", mockHandler).Name("api.user")
+
+	// 只删除对应方法和域名的路由的名字
+	assert.True(t, h.RemoveRoute(http.MethodGet, "/user/:id"))
+	_, err := h.URL("user", "id", "1")
+	assert.EqualError(t, err, "web: 找不到名字为 [user] 的路由")
+	_, err = h.URL("user.update", "id", "1")
+	assert.NoError(t, err)
+	_, err = h.URL("api.user", "id", "1")
+	assert.NoError(t, err)
+
+	assert.True(t, api.RemoveRoute(http.MethodGet, "/user/:id"))
+	_, err = h.URL("api.user", "id", "1")
+	assert.EqualError(t, err, "web: 找不到名字为 [api.user] 的路由")
+
+	// 名字删除之后可以重新使用
+	h.Get("/account/:id", mockHandler).Name("user")
+	u, err := h.URL("user", "id", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "/account/1", u)
+}